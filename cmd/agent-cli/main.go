@@ -0,0 +1,97 @@
+// agent-cli 命令行中与 react agent 多轮对话。
+//
+// 模型配置可写在 -config 指定的 yaml 文件中（provider 支持 ark、openai、ollama），环境变量 ARK_API_KEY、ARK_MODEL、ARK_BASE_URL 优先于配置文件。
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/birdy/agent/pkg/t_eino"
+	"github.com/cloudwego/eino/schema"
+	"gopkg.in/yaml.v3"
+)
+
+type config struct {
+	Provider     string            `yaml:"provider"` //ark、openai、ollama，默认 ark
+	APIKey       string            `yaml:"api_key"`
+	Model        string            `yaml:"model"`
+	BaseURL      string            `yaml:"base_url"`
+	Headers      map[string]string `yaml:"headers"`
+	SystemPrompt string            `yaml:"system_prompt"`
+	MaxStep      int               `yaml:"max_step"`
+	FollowUp     int               `yaml:"follow_up"` //每轮回答后推荐的问题数量，0 不推荐
+}
+
+func loadConfig(path string) (*config, error) {
+	c := &config{Provider: t_eino.ModelProviderArk, MaxStep: 20}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err = yaml.Unmarshal(data, c); err != nil {
+			return nil, fmt.Errorf("parse config %s fail: %w", path, err)
+		}
+	}
+	for env, field := range map[string]*string{"ARK_API_KEY": &c.APIKey, "ARK_MODEL": &c.Model, "ARK_BASE_URL": &c.BaseURL} {
+		if v := os.Getenv(env); v != "" {
+			*field = v
+		}
+	}
+	if c.Model == "" {
+		return nil, fmt.Errorf("model is required, set ARK_MODEL or model in config")
+	}
+	return c, nil
+}
+
+// newAgent 按配置创建 agent，/model 切换模型时重新创建
+func newAgent(ctx context.Context, c *config) (*t_eino.Agent, error) {
+	llm, err := t_eino.NewChatModel(ctx, &t_eino.ModelConfig{
+		Provider: c.Provider,
+		APIKey:   c.APIKey,
+		Model:    c.Model,
+		BaseURL:  c.BaseURL,
+		Headers:  c.Headers,
+	})
+	if err != nil {
+		return nil, err
+	}
+	agentConfig := &t_eino.AgentConfig{
+		ToolCallingModel: llm,
+		MaxStep:          c.MaxStep,
+	}
+	if c.FollowUp > 0 {
+		agentConfig.FollowUp = &t_eino.FollowUpConfig{Count: c.FollowUp}
+	}
+	if c.SystemPrompt != "" {
+		agentConfig.MessageModifier = func(_ context.Context, input []*schema.Message) []*schema.Message {
+			return append([]*schema.Message{schema.SystemMessage(c.SystemPrompt)}, input...)
+		}
+	}
+	return t_eino.NewAgent(ctx, agentConfig)
+}
+
+func main() {
+	configPath := flag.String("config", "", "config file (yaml)")
+	flag.Parse()
+
+	c, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	ctx := context.Background()
+	agent, err := newAgent(ctx, c)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "create agent fail:", err)
+		os.Exit(1)
+	}
+	r := &repl{config: c, agent: agent, newAgent: newAgent, in: os.Stdin, out: os.Stdout}
+	if err = r.run(ctx); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/birdy/agent/pkg/t_eino"
+	"github.com/cloudwego/eino/schema"
+)
+
+const (
+	collapsedWidth = 80
+
+	colorDim   = "\033[2m"
+	colorReset = "\033[0m"
+)
+
+const helpText = `/tools          列出 config tools 和上一轮动态获取的 tool
+/reset          清空对话历史
+/save <file>    保存对话历史
+/load <file>    加载对话历史
+/model [name]   查看或切换模型
+/expand         展开上一轮 tool call 的完整参数和结果
+/help           查看帮助
+/exit           退出`
+
+// toolTrace 一次 tool call 的参数和结果，默认折叠为一行输出
+type toolTrace struct {
+	id        string
+	name      string
+	arguments string
+	result    string
+}
+
+type repl struct {
+	config   *config
+	agent    *t_eino.Agent
+	newAgent func(ctx context.Context, c *config) (*t_eino.Agent, error)
+	in       io.Reader
+	out      io.Writer
+
+	history      []*schema.Message
+	lastTraces   []*toolTrace
+	dynamicTools []string //上一轮结束时动态获取的 tool
+}
+
+func (r *repl) run(ctx context.Context) error {
+	fmt.Fprintf(r.out, "model: %s，输入 /help 查看命令，Ctrl+C 中断当前回答\n", r.config.Model)
+	scanner := bufio.NewScanner(r.in)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for {
+		fmt.Fprint(r.out, "> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(r.out)
+			return scanner.Err()
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "/") {
+			quit, err := r.command(ctx, line)
+			if err != nil {
+				fmt.Fprintln(r.out, "error:", err)
+			}
+			if quit {
+				return nil
+			}
+			continue
+		}
+		r.turn(ctx, line)
+	}
+}
+
+func (r *repl) command(ctx context.Context, line string) (quit bool, err error) {
+	fields := strings.Fields(line)
+	arg := ""
+	if len(fields) > 1 {
+		arg = fields[1]
+	}
+	switch fields[0] {
+	case "/exit", "/quit":
+		return true, nil
+	case "/help":
+		fmt.Fprintln(r.out, helpText)
+	case "/tools":
+		for _, tl := range r.agent.Tools() {
+			info, err := tl.Info(ctx)
+			if err != nil {
+				return false, err
+			}
+			fmt.Fprintf(r.out, "%s: %s\n", info.Name, collapse(info.Desc))
+		}
+		if len(r.dynamicTools) > 0 {
+			fmt.Fprintf(r.out, "动态获取: %s\n", strings.Join(r.dynamicTools, ", "))
+		}
+	case "/reset":
+		r.history, r.lastTraces, r.dynamicTools = nil, nil, nil
+		fmt.Fprintln(r.out, "已清空对话历史")
+	case "/save":
+		if arg == "" {
+			return false, fmt.Errorf("usage: /save <file>")
+		}
+		data, err := json.MarshalIndent(r.history, "", "  ")
+		if err != nil {
+			return false, err
+		}
+		if err = os.WriteFile(arg, data, 0o644); err != nil {
+			return false, err
+		}
+		fmt.Fprintf(r.out, "已保存 %d 条消息到 %s\n", len(r.history), arg)
+	case "/load":
+		if arg == "" {
+			return false, fmt.Errorf("usage: /load <file>")
+		}
+		data, err := os.ReadFile(arg)
+		if err != nil {
+			return false, err
+		}
+		var history []*schema.Message
+		if err = json.Unmarshal(data, &history); err != nil {
+			return false, fmt.Errorf("parse %s fail: %w", arg, err)
+		}
+		r.history, r.lastTraces, r.dynamicTools = history, nil, nil
+		fmt.Fprintf(r.out, "已加载 %d 条消息\n", len(history))
+	case "/model":
+		if arg == "" {
+			fmt.Fprintln(r.out, r.config.Model)
+			return false, nil
+		}
+		c := *r.config
+		c.Model = arg
+		agent, err := r.newAgent(ctx, &c)
+		if err != nil {
+			return false, err
+		}
+		r.config, r.agent = &c, agent
+		fmt.Fprintf(r.out, "已切换到 %s\n", arg)
+	case "/expand":
+		for _, t := range r.lastTraces {
+			fmt.Fprintf(r.out, "▾ %s(%s) [%s]\n%s\n", t.name, t.arguments, t.id, t.result)
+		}
+	default:
+		return false, fmt.Errorf("unknown command %s, see /help", fields[0])
+	}
+	return false, nil
+}
+
+// turn 运行一轮对话，成功后将本轮的消息（包含 tool call 和 tool 结果）加入历史
+func (r *repl) turn(ctx context.Context, line string) {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+
+	input := append(append([]*schema.Message(nil), r.history...), schema.UserMessage(line))
+	iter, err := r.agent.StreamEvents(ctx, input)
+	if err != nil {
+		fmt.Fprintln(r.out, "error:", err)
+		return
+	}
+	defer iter.Close()
+
+	var (
+		messages  = []*schema.Message{schema.UserMessage(line)}
+		traces    []*toolTrace
+		reasoning bool
+	)
+	traceByID := make(map[string]*toolTrace)
+	for {
+		e, ok := iter.Next()
+		if !ok {
+			break
+		}
+		switch e.Type {
+		case t_eino.EventReasoningDelta:
+			if !reasoning {
+				reasoning = true
+				fmt.Fprint(r.out, colorDim)
+			}
+			fmt.Fprint(r.out, e.Delta)
+		case t_eino.EventTextDelta:
+			r.endReasoning(&reasoning)
+			fmt.Fprint(r.out, e.Delta)
+		case t_eino.EventToolCallFinished:
+			r.endReasoning(&reasoning)
+			t := &toolTrace{id: e.ToolCallID, name: e.ToolName, arguments: e.Arguments}
+			traces = append(traces, t)
+			traceByID[t.id] = t
+			fmt.Fprintf(r.out, "\n%s▸ %s(%s)%s\n", colorDim, t.name, collapse(t.arguments), colorReset)
+		case t_eino.EventToolResult:
+			if t, ok := traceByID[e.ToolCallID]; ok {
+				t.result = e.Result
+			}
+			fmt.Fprintf(r.out, "%s  ↳ %s%s\n", colorDim, collapse(e.Result), colorReset)
+			messages = append(messages, schema.ToolMessage(e.Result, e.ToolCallID, schema.WithToolName(e.ToolName)))
+		case t_eino.EventStepFinished:
+			r.endReasoning(&reasoning)
+			messages = append(messages, e.Message)
+			if e.Session != nil {
+				r.dynamicTools = e.Session.DynamicTools
+			}
+		case t_eino.EventFollowUpQuestions:
+			// 在 run_finished 之后输出
+			fmt.Fprintf(r.out, "\n%s你可能还想问：", colorDim)
+			for i, q := range e.Questions {
+				fmt.Fprintf(r.out, "\n  %d. %s", i+1, q)
+			}
+			fmt.Fprintln(r.out, colorReset)
+		case t_eino.EventRunFinished:
+			fmt.Fprintln(r.out)
+			r.history = append(r.history, messages...)
+			r.lastTraces = traces
+		case t_eino.EventRunError:
+			r.endReasoning(&reasoning)
+			if t_eino.IsCanceled(e.Err) {
+				fmt.Fprintln(r.out, "\n已中断，本轮不计入历史")
+			} else {
+				fmt.Fprintln(r.out, "\nerror:", e.Err)
+			}
+			r.lastTraces = traces
+		}
+	}
+}
+
+func (r *repl) endReasoning(reasoning *bool) {
+	if *reasoning {
+		*reasoning = false
+		fmt.Fprint(r.out, colorReset+"\n")
+	}
+}
+
+// collapse 折叠为一行，超出 collapsedWidth 个字符时截断
+func collapse(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if runes := []rune(s); len(runes) > collapsedWidth {
+		return string(runes[:collapsedWidth]) + "…"
+	}
+	return s
+}
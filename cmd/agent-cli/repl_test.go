@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/birdy/agent/pkg/t_eino"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/components/tool/utils"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+)
+
+// scriptedModel 按输入消息返回预设的回复
+type scriptedModel struct {
+	reply func(in []*schema.Message) *schema.Message
+}
+
+func (m *scriptedModel) Generate(ctx context.Context, in []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	return m.reply(in), nil
+}
+
+func (m *scriptedModel) Stream(ctx context.Context, in []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	return schema.StreamReaderFromArray([]*schema.Message{m.reply(in)}), nil
+}
+
+func (m *scriptedModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	return m, nil
+}
+
+func toolCall(id, name, args string) *schema.Message {
+	return schema.AssistantMessage("", []schema.ToolCall{{ID: id, Function: schema.FunctionCall{Name: name, Arguments: args}}})
+}
+
+// newTestTool 返回 "<name> got <q>" 的 tool，q 为 fail 时失败
+func newTestTool(t *testing.T, name string) tool.BaseTool {
+	t.Helper()
+	tl, err := utils.InferTool(name, name, func(ctx context.Context, in struct {
+		Q string `json:"q"`
+	}) (string, error) {
+		if in.Q == "fail" {
+			return "", errors.New("boom")
+		}
+		return name + " got " + in.Q, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tl
+}
+
+// dumpMessages 按顺序拼接消息，格式为 "<role>:<content>"
+func dumpMessages(msgs []*schema.Message) string {
+	parts := make([]string, 0, len(msgs))
+	for _, msg := range msgs {
+		parts = append(parts, string(msg.Role)+":"+msg.Content)
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestTurn(t *testing.T) {
+	ctx := context.Background()
+	// 用户输入 lookup 时获取并调用 lookup，输入 fail 时调用 echo 失败，否则回答本次输入的消息数个 m
+	llm := &scriptedModel{reply: func(in []*schema.Message) *schema.Message {
+		last := in[len(in)-1]
+		switch {
+		case last.Role == schema.User && last.Content == "lookup":
+			return toolCall("g", t_eino.SpecialGetToolToolName, `{"name":"lookup"}`)
+		case last.Role == schema.User && last.Content == "fail":
+			return toolCall("f", "echo", `{"q":"fail"}`)
+		case last.Role == schema.Tool && last.ToolCallID == "g":
+			return toolCall("l", "lookup", `{"q":"x"}`)
+		}
+		return schema.AssistantMessage(strings.Repeat("m", len(in)), nil)
+	}}
+	followUp := &scriptedModel{reply: func(in []*schema.Message) *schema.Message {
+		return schema.AssistantMessage(`{"questions":["q1"]}`, nil)
+	}}
+	tests := []struct {
+		name     string
+		followUp bool
+		lines    []string
+		// wantHistory 所有轮次结束后的历史
+		wantHistory string
+		wantDynamic []string
+		// wantOutput 依次出现在输出中
+		wantOutput []string
+	}{
+		{
+			name:        "history appended on run finished",
+			lines:       []string{"hi", "again"},
+			wantHistory: "user:hi assistant:m user:again assistant:mmm",
+			wantOutput:  []string{"m\n", "mmm\n"},
+		},
+		{
+			name:  "dynamic tools tracked",
+			lines: []string{"lookup"},
+			wantHistory: "user:lookup assistant: tool:get tool lookup success assistant: tool:lookup got x " +
+				"assistant:mmmmm",
+			wantDynamic: []string{"lookup"},
+			wantOutput:  []string{"▸ " + t_eino.SpecialGetToolToolName, "▸ lookup", "↳ lookup got x", "mmmmm"},
+		},
+		{
+			name:        "failed turn not in history",
+			lines:       []string{"hi", "fail"},
+			wantHistory: "user:hi assistant:m",
+			wantOutput:  []string{"m\n", "▸ echo", "error:"},
+		},
+		{
+			name:        "follow up after run finished",
+			followUp:    true,
+			lines:       []string{"hi"},
+			wantHistory: "user:hi assistant:m",
+			wantOutput:  []string{"m\n", "你可能还想问：", "1. q1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agentConfig := &t_eino.AgentConfig{
+				ToolCallingModel: llm,
+				ToolsConfig:      compose.ToolsNodeConfig{Tools: []tool.BaseTool{newTestTool(t, "echo")}},
+				ExtraTools:       []tool.BaseTool{newTestTool(t, "lookup")},
+			}
+			if tt.followUp {
+				agentConfig.FollowUp = &t_eino.FollowUpConfig{Model: followUp}
+			}
+			a, err := t_eino.NewAgent(ctx, agentConfig)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out := &bytes.Buffer{}
+			r := &repl{config: &config{Model: "m"}, agent: a, out: out}
+			for _, line := range tt.lines {
+				r.turn(ctx, line)
+			}
+			if got := dumpMessages(r.history); got != tt.wantHistory {
+				t.Fatalf("got history %q, want %q", got, tt.wantHistory)
+			}
+			if strings.Join(r.dynamicTools, ",") != strings.Join(tt.wantDynamic, ",") {
+				t.Fatalf("got dynamic tools %v, want %v", r.dynamicTools, tt.wantDynamic)
+			}
+			rest := out.String()
+			for _, want := range tt.wantOutput {
+				i := strings.Index(rest, want)
+				if i < 0 {
+					t.Fatalf("output %q does not contain %q in order", out.String(), want)
+				}
+				rest = rest[i+len(want):]
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+// agentd 以 OpenAI 兼容的 /v1/chat/completions 接口对外提供 react agent，agent 由 -agent 指定的定义文件创建。
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/birdy/agent/pkg/t_eino"
+)
+
+func main() {
+	addr := flag.String("addr", ":8080", "listen address")
+	agentPath := flag.String("agent", "agent.yaml", "agent definition file (yaml or json)")
+	flag.Parse()
+
+	ctx := context.Background()
+	def, err := t_eino.ReadAgentDefinition(*agentPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	config, err := def.AgentConfig(ctx, t_eino.DefaultRegistry)
+	if err != nil {
+		log.Fatalf("agent %s: %v", def.Name, err)
+	}
+	agent, err := t_eino.NewAgent(ctx, config)
+	if err != nil {
+		log.Fatalf("create agent fail: %v", err)
+	}
+
+	log.Printf("agentd serving agent %s on %s", def.Name, *addr)
+	if err = http.ListenAndServe(*addr, newServer(agent, os.ExpandEnv(def.Model.Model))); err != nil {
+		log.Fatal(err)
+	}
+}
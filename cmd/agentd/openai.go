@@ -0,0 +1,332 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/birdy/agent/pkg/t_eino"
+	"github.com/cloudwego/eino/schema"
+)
+
+// OpenAI chat completions 协议中用到的部分
+
+type chatCompletionRequest struct {
+	Model         string         `json:"model"`
+	Messages      []*chatMessage `json:"messages"`
+	Stream        bool           `json:"stream"`
+	StreamOptions *struct {
+		IncludeUsage bool `json:"include_usage"`
+	} `json:"stream_options,omitempty"`
+}
+
+type chatMessage struct {
+	Role       string          `json:"role"`
+	Content    json.RawMessage `json:"content,omitempty"` //字符串或 content part 数组，只支持 text part
+	Name       string          `json:"name,omitempty"`
+	ToolCalls  []chatToolCall  `json:"tool_calls,omitempty"`
+	ToolCallID string          `json:"tool_call_id,omitempty"`
+}
+
+type chatToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+type chatCompletion struct {
+	ID      string        `json:"id"`
+	Object  string        `json:"object"`
+	Created int64         `json:"created"`
+	Model   string        `json:"model"`
+	Choices []*chatChoice `json:"choices"`
+	Usage   *chatUsage    `json:"usage,omitempty"`
+}
+
+type chatChoice struct {
+	Index        int                `json:"index"`
+	Message      *chatOutputMessage `json:"message,omitempty"`
+	Delta        *chatOutputMessage `json:"delta,omitempty"`
+	FinishReason *string            `json:"finish_reason"`
+}
+
+type chatOutputMessage struct {
+	Role    string `json:"role,omitempty"`
+	Content string `json:"content"`
+}
+
+type chatUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+type chatError struct {
+	Error struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+		Code    string `json:"code,omitempty"`
+	} `json:"error"`
+}
+
+// server 以 OpenAI chat completions 协议对外提供 agent，只返回最终的 assistant 消息，内部的 tool call 不对外暴露
+type server struct {
+	agent *t_eino.Agent
+	model string //请求未指定 model 时响应中使用的名称
+}
+
+func newServer(agent *t_eino.Agent, model string) http.Handler {
+	s := &server{agent: agent, model: model}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/chat/completions", s.chatCompletions)
+	return mux
+}
+
+func (s *server) chatCompletions(w http.ResponseWriter, r *http.Request) {
+	req := &chatCompletionRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "", fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+	messages, err := toSchemaMessages(req.Messages)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "", err.Error())
+		return
+	}
+	if req.Model == "" {
+		req.Model = s.model
+	}
+
+	iter, err := s.agent.StreamEvents(r.Context(), messages)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "server_error", "", err.Error())
+		return
+	}
+	defer iter.Close()
+
+	if req.Stream {
+		s.stream(w, req, iter)
+		return
+	}
+
+	run := &runResult{}
+	for {
+		e, ok := iter.Next()
+		if !ok {
+			break
+		}
+		run.add(e)
+	}
+	if run.err != nil {
+		writeError(w, http.StatusInternalServerError, "server_error", errorCode(run.err), run.err.Error())
+		return
+	}
+	stop := "stop"
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(&chatCompletion{
+		ID:      "chatcmpl-" + run.id,
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   req.Model,
+		Choices: []*chatChoice{{Message: &chatOutputMessage{Role: string(schema.Assistant), Content: run.output.Content}, FinishReason: &stop}},
+		Usage:   &run.usage,
+	})
+}
+
+func (s *server) stream(w http.ResponseWriter, req *chatCompletionRequest, iter *t_eino.EventIterator) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+
+	run := &runResult{}
+	created := time.Now().Unix()
+	chunk := func(choices []*chatChoice, usage *chatUsage) *chatCompletion {
+		return &chatCompletion{ID: "chatcmpl-" + run.id, Object: "chat.completion.chunk", Created: created, Model: req.Model, Choices: choices, Usage: usage}
+	}
+	write := func(v any) bool {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return false
+		}
+		if _, err = fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+			return false
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return true
+	}
+
+	// 一步的正文增量先缓存，该步没有 tool call 时才是最终结果并输出，否则是内部步骤的正文，丢弃
+	var deltas []string
+	streamed := false //最终结果是否已经以正文增量输出
+	for {
+		e, ok := iter.Next()
+		if !ok {
+			break
+		}
+		run.add(e)
+		switch e.Type {
+		case t_eino.EventRunStarted:
+			if !write(chunk([]*chatChoice{{Delta: &chatOutputMessage{Role: string(schema.Assistant)}}}, nil)) {
+				return
+			}
+		case t_eino.EventStepStarted:
+			deltas = deltas[:0]
+		case t_eino.EventTextDelta:
+			deltas = append(deltas, e.Delta)
+		case t_eino.EventStepFinished:
+			if e.Message != nil && len(e.Message.ToolCalls) > 0 {
+				deltas = deltas[:0]
+				continue
+			}
+			for _, delta := range deltas {
+				streamed = true
+				if !write(chunk([]*chatChoice{{Delta: &chatOutputMessage{Content: delta}}}, nil)) {
+					return
+				}
+			}
+			deltas = deltas[:0]
+		}
+	}
+
+	if run.err != nil {
+		ce := &chatError{}
+		ce.Error.Message, ce.Error.Type, ce.Error.Code = run.err.Error(), "server_error", errorCode(run.err)
+		write(ce)
+		return
+	}
+	// return directly 的 tool 结果没有正文增量
+	if !streamed && run.output.Content != "" {
+		if !write(chunk([]*chatChoice{{Delta: &chatOutputMessage{Content: run.output.Content}}}, nil)) {
+			return
+		}
+	}
+	stop := "stop"
+	if !write(chunk([]*chatChoice{{Delta: &chatOutputMessage{}, FinishReason: &stop}}, nil)) {
+		return
+	}
+	if req.StreamOptions != nil && req.StreamOptions.IncludeUsage {
+		if !write(chunk([]*chatChoice{}, &run.usage)) {
+			return
+		}
+	}
+	_, _ = fmt.Fprint(w, "data: [DONE]\n\n")
+	if flusher != nil {
+		flusher.Flush()
+	}
+}
+
+// runResult 汇总一次运行的结果，usage 为所有 ChatModel 调用之和
+type runResult struct {
+	id     string
+	output *schema.Message
+	usage  chatUsage
+	err    error
+}
+
+func (r *runResult) add(e *t_eino.AgentEvent) {
+	switch e.Type {
+	case t_eino.EventRunStarted:
+		r.id = e.RunID
+	case t_eino.EventStepFinished:
+		if e.Message.ResponseMeta != nil && e.Message.ResponseMeta.Usage != nil {
+			u := e.Message.ResponseMeta.Usage
+			r.usage.PromptTokens += u.PromptTokens
+			r.usage.CompletionTokens += u.CompletionTokens
+			r.usage.TotalTokens += u.TotalTokens
+		}
+	case t_eino.EventRunFinished:
+		r.output = e.Message
+	case t_eino.EventRunError:
+		r.err = e.Err
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, typ, code, msg string) {
+	ce := &chatError{}
+	ce.Error.Message, ce.Error.Type, ce.Error.Code = msg, typ, code
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(ce)
+}
+
+func errorCode(err error) string {
+	var approval *t_eino.ApprovalRequiredError
+	switch {
+	case errors.As(err, &approval):
+		return "approval_required"
+	case t_eino.IsMaxStepsExceeded(err):
+		return "max_steps_exceeded"
+	case t_eino.IsModelFailed(err):
+		return "model_failed"
+	case t_eino.IsCanceled(err):
+		return "canceled"
+	}
+	return ""
+}
+
+func toSchemaMessages(messages []*chatMessage) ([]*schema.Message, error) {
+	result := make([]*schema.Message, 0, len(messages))
+	for i, m := range messages {
+		content, err := messageContent(m.Content)
+		if err != nil {
+			return nil, fmt.Errorf("messages[%d]: %w", i, err)
+		}
+		switch m.Role {
+		case "system", "developer":
+			result = append(result, schema.SystemMessage(content))
+		case "user":
+			result = append(result, schema.UserMessage(content))
+		case "assistant":
+			var toolCalls []schema.ToolCall
+			for _, tc := range m.ToolCalls {
+				toolCalls = append(toolCalls, schema.ToolCall{
+					ID:       tc.ID,
+					Type:     "function",
+					Function: schema.FunctionCall{Name: tc.Function.Name, Arguments: tc.Function.Arguments},
+				})
+			}
+			result = append(result, schema.AssistantMessage(content, toolCalls))
+		case "tool":
+			result = append(result, schema.ToolMessage(content, m.ToolCallID))
+		default:
+			return nil, fmt.Errorf("messages[%d]: unsupported role %q", i, m.Role)
+		}
+	}
+	return result, nil
+}
+
+// messageContent content 为字符串或 [{"type":"text","text":"..."}]
+func messageContent(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return "", nil
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s, nil
+	}
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(raw, &parts); err != nil {
+		return "", fmt.Errorf("invalid content: %w", err)
+	}
+	var sb strings.Builder
+	for _, p := range parts {
+		if p.Type != "text" {
+			return "", fmt.Errorf("unsupported content part type %q", p.Type)
+		}
+		sb.WriteString(p.Text)
+	}
+	return sb.String(), nil
+}
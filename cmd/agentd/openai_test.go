@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/birdy/agent/pkg/t_eino"
+	"github.com/cloudwego/eino/callbacks"
+	"github.com/cloudwego/eino/components"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/components/tool/utils"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+)
+
+// scriptedModel 按调用次数依次返回 replies，流式输出时没有 tool call 的正文按空格拆成多个 chunk
+type scriptedModel struct {
+	replies []*schema.Message
+	calls   int
+}
+
+func (m *scriptedModel) Generate(ctx context.Context, in []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	msg := *m.replies[m.calls%len(m.replies)]
+	m.calls++
+	msg.ResponseMeta = &schema.ResponseMeta{Usage: &schema.TokenUsage{PromptTokens: 10, CompletionTokens: 2, TotalTokens: 12}}
+	return &msg, nil
+}
+
+func (m *scriptedModel) Stream(ctx context.Context, in []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	msg, err := m.Generate(ctx, in, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if len(msg.ToolCalls) > 0 {
+		return schema.StreamReaderFromArray([]*schema.Message{msg}), nil
+	}
+	var chunks []*schema.Message
+	for _, word := range strings.SplitAfter(msg.Content, " ") {
+		chunks = append(chunks, schema.AssistantMessage(word, nil))
+	}
+	chunks[len(chunks)-1].ResponseMeta = msg.ResponseMeta
+	return schema.StreamReaderFromArray(chunks), nil
+}
+
+func (m *scriptedModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	return m, nil
+}
+
+// callbackModel 与真实的模型一样自己触发 callbacks
+type callbackModel struct {
+	*scriptedModel
+}
+
+func (m *callbackModel) IsCallbacksEnabled() bool {
+	return true
+}
+
+func (m *callbackModel) Generate(ctx context.Context, in []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	ctx = callbacks.EnsureRunInfo(ctx, "Callback", components.ComponentOfChatModel)
+	ctx = callbacks.OnStart(ctx, &model.CallbackInput{Messages: in})
+	msg, err := m.scriptedModel.Generate(ctx, in, opts...)
+	if err != nil {
+		callbacks.OnError(ctx, err)
+		return nil, err
+	}
+	callbacks.OnEnd(ctx, &model.CallbackOutput{Message: msg})
+	return msg, nil
+}
+
+func (m *callbackModel) Stream(ctx context.Context, in []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	ctx = callbacks.EnsureRunInfo(ctx, "Callback", components.ComponentOfChatModel)
+	ctx = callbacks.OnStart(ctx, &model.CallbackInput{Messages: in})
+	sr, err := m.scriptedModel.Stream(ctx, in, opts...)
+	if err != nil {
+		callbacks.OnError(ctx, err)
+		return nil, err
+	}
+	_, out := callbacks.OnEndWithStreamOutput(ctx, schema.StreamReaderWithConvert(sr, func(msg *schema.Message) (*model.CallbackOutput, error) {
+		return &model.CallbackOutput{Message: msg}, nil
+	}))
+	return schema.StreamReaderWithConvert(out, func(o *model.CallbackOutput) (*schema.Message, error) {
+		return o.Message, nil
+	}), nil
+}
+
+func (m *callbackModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	return m, nil
+}
+
+func toolCall(id, name, args string) schema.ToolCall {
+	return schema.ToolCall{ID: id, Function: schema.FunctionCall{Name: name, Arguments: args}}
+}
+
+func newTestServer(t *testing.T, llm model.ToolCallingChatModel) *httptest.Server {
+	t.Helper()
+	ctx := context.Background()
+	echo, err := utils.InferTool("echo", "echo q", func(ctx context.Context, in struct {
+		Q string `json:"q"`
+	}) (string, error) {
+		return "got " + in.Q, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	agent, err := t_eino.NewAgent(ctx, &t_eino.AgentConfig{
+		ToolCallingModel: llm,
+		MaxStep:          8,
+		ToolsConfig:      compose.ToolsNodeConfig{Tools: []tool.BaseTool{echo}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(newServer(agent, "test-model"))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func postCompletion(t *testing.T, srv *httptest.Server, body string) *http.Response {
+	t.Helper()
+	resp, err := http.Post(srv.URL+"/v1/chat/completions", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = resp.Body.Close() })
+	return resp
+}
+
+// readStream 拼接流式响应的正文，返回正文、usage 和是否以 [DONE] 结束
+func readStream(t *testing.T, resp *http.Response) (string, *chatUsage, bool) {
+	t.Helper()
+	var (
+		content strings.Builder
+		usage   *chatUsage
+	)
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		if data == "[DONE]" {
+			return content.String(), usage, true
+		}
+		chunk := &chatCompletion{}
+		if err := json.Unmarshal([]byte(data), chunk); err != nil {
+			t.Fatalf("invalid chunk %s: %v", data, err)
+		}
+		for _, choice := range chunk.Choices {
+			if choice.Delta != nil {
+				content.WriteString(choice.Delta.Content)
+			}
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+	}
+	return content.String(), usage, false
+}
+
+func TestChatCompletionsStreamParity(t *testing.T) {
+	tests := []struct {
+		name    string
+		replies []*schema.Message
+		want    string
+	}{
+		{
+			name:    "direct answer",
+			replies: []*schema.Message{schema.AssistantMessage("hello there", nil)},
+			want:    "hello there",
+		},
+		{
+			// 中间步骤的正文不能输出
+			name: "intermediate step text",
+			replies: []*schema.Message{
+				schema.AssistantMessage("let me check ", []schema.ToolCall{toolCall("c1", "echo", `{"q":"a"}`)}),
+				schema.AssistantMessage("the answer is a", nil),
+			},
+			want: "the answer is a",
+		},
+		{
+			name: "multiple tool steps",
+			replies: []*schema.Message{
+				schema.AssistantMessage("step one ", []schema.ToolCall{toolCall("c1", "echo", `{"q":"a"}`)}),
+				schema.AssistantMessage("step two ", []schema.ToolCall{toolCall("c2", "echo", `{"q":"b"}`)}),
+				schema.AssistantMessage("done", nil),
+			},
+			want: "done",
+		},
+	}
+	for _, tt := range tests {
+		// 每次调用 ChatModel 的 usage 为 10+2，每一步只能计入一次
+		wantUsage := chatUsage{PromptTokens: 10 * len(tt.replies), CompletionTokens: 2 * len(tt.replies), TotalTokens: 12 * len(tt.replies)}
+		models := []struct {
+			name string
+			new  func() model.ToolCallingChatModel
+		}{
+			{name: "scripted", new: func() model.ToolCallingChatModel { return &scriptedModel{replies: tt.replies} }},
+			{name: "callbacks", new: func() model.ToolCallingChatModel { return &callbackModel{&scriptedModel{replies: tt.replies}} }},
+		}
+		for _, m := range models {
+			t.Run(tt.name+"/"+m.name, func(t *testing.T) {
+				resp := postCompletion(t, newTestServer(t, m.new()), `{"messages":[{"role":"user","content":"hi"}]}`)
+				if resp.StatusCode != http.StatusOK {
+					t.Fatalf("non-stream status %d", resp.StatusCode)
+				}
+				completion := &chatCompletion{}
+				if err := json.NewDecoder(resp.Body).Decode(completion); err != nil {
+					t.Fatal(err)
+				}
+				if got := completion.Choices[0].Message.Content; got != tt.want {
+					t.Fatalf("non-stream content %q, want %q", got, tt.want)
+				}
+				if *completion.Usage != wantUsage {
+					t.Fatalf("non-stream usage %v, want %v", completion.Usage, wantUsage)
+				}
+
+				resp = postCompletion(t, newTestServer(t, m.new()),
+					`{"stream":true,"stream_options":{"include_usage":true},"messages":[{"role":"user","content":[{"type":"text","text":"hi"}]}]}`)
+				if resp.StatusCode != http.StatusOK {
+					t.Fatalf("stream status %d", resp.StatusCode)
+				}
+				content, usage, done := readStream(t, resp)
+				if !done {
+					t.Fatal("stream is not finished with [DONE]")
+				}
+				if content != tt.want {
+					t.Fatalf("stream content %q, want %q", content, tt.want)
+				}
+				if usage == nil || *usage != wantUsage {
+					t.Fatalf("stream usage %v, want %v", usage, wantUsage)
+				}
+			})
+		}
+	}
+}
+
+func TestChatCompletionsBadRequest(t *testing.T) {
+	srv := newTestServer(t, &scriptedModel{replies: []*schema.Message{schema.AssistantMessage("unused", nil)}})
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "invalid json", body: `{`},
+		{name: "unsupported role", body: `{"messages":[{"role":"bad","content":"hi"}]}`},
+		{name: "unsupported content part", body: `{"messages":[{"role":"user","content":[{"type":"image_url"}]}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := postCompletion(t, srv, tt.body)
+			if resp.StatusCode != http.StatusBadRequest {
+				t.Fatalf("status %d, want %d", resp.StatusCode, http.StatusBadRequest)
+			}
+			ce := &chatError{}
+			if err := json.NewDecoder(resp.Body).Decode(ce); err != nil {
+				t.Fatal(err)
+			}
+			if ce.Error.Type != "invalid_request_error" {
+				t.Fatalf("error type %q", ce.Error.Type)
+			}
+		})
+	}
+}
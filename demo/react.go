@@ -2,8 +2,8 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"github.com/birdy/agent/pkg/t_eino"
-	"github.com/cloudwego/eino-ext/components/model/ark"
 	"github.com/cloudwego/eino/components/tool"
 	"github.com/cloudwego/eino/components/tool/utils"
 	"github.com/cloudwego/eino/compose"
@@ -13,12 +13,15 @@ import (
 func main() {
 	ctx := context.Background()
 
-	llm, err := ark.NewChatModel(context.Background(), &ark.ChatModelConfig{
-		// Add Ark-specific configuration from environment variables
-		APIKey:  "{APIKey}",
-		Model:   "{Model}",
-		BaseURL: "{BaseURL}",
+	llm, err := t_eino.NewChatModel(ctx, &t_eino.ModelConfig{
+		Provider: t_eino.ModelProviderArk, //也可以是 openai（及兼容接口）、ollama
+		APIKey:   "{APIKey}",
+		Model:    "{Model}",
+		BaseURL:  "{BaseURL}",
 	})
+	if err != nil {
+		return
+	}
 
 	exmapleTool, err := utils.InferTool("tool", "desc", func(ctx context.Context, input any) (output string, err error) {
 		return "", nil
@@ -44,6 +47,30 @@ func main() {
 
 	var input []*schema.Message
 
-	reactAgent.Stream(ctx, input, opt)
+	iter, err := reactAgent.Stream(ctx, input, opt)
+	if err != nil {
+		return
+	}
+	defer iter.Close()
 	//下面就是输出
+	for {
+		chunk, ok := iter.Next()
+		if !ok {
+			break
+		}
+		if chunk.Done {
+			if chunk.Err != nil {
+				fmt.Println("run fail:", chunk.Err)
+			}
+			break
+		}
+		for {
+			msg, err := chunk.Message.Recv()
+			if err != nil {
+				break
+			}
+			fmt.Print(msg.Content)
+		}
+		chunk.Message.Close()
+	}
 }
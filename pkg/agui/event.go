@@ -0,0 +1,205 @@
+package agui
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/birdy/agent/pkg/t_eino"
+	"github.com/google/uuid"
+)
+
+// EventType AG-UI 协议的事件类型
+type EventType string
+
+const (
+	EventRunStarted          EventType = "RUN_STARTED"
+	EventRunFinished         EventType = "RUN_FINISHED"
+	EventRunError            EventType = "RUN_ERROR"
+	EventStepStarted         EventType = "STEP_STARTED"
+	EventStepFinished        EventType = "STEP_FINISHED"
+	EventTextMessageStart    EventType = "TEXT_MESSAGE_START"
+	EventTextMessageContent  EventType = "TEXT_MESSAGE_CONTENT"
+	EventTextMessageEnd      EventType = "TEXT_MESSAGE_END"
+	EventThinkingStart       EventType = "THINKING_START"
+	EventThinkingEnd         EventType = "THINKING_END"
+	EventThinkingTextStart   EventType = "THINKING_TEXT_MESSAGE_START"
+	EventThinkingTextContent EventType = "THINKING_TEXT_MESSAGE_CONTENT"
+	EventThinkingTextEnd     EventType = "THINKING_TEXT_MESSAGE_END"
+	EventToolCallStart       EventType = "TOOL_CALL_START"
+	EventToolCallArgs        EventType = "TOOL_CALL_ARGS"
+	EventToolCallEnd         EventType = "TOOL_CALL_END"
+	EventToolCallResult      EventType = "TOOL_CALL_RESULT"
+	EventStateSnapshot       EventType = "STATE_SNAPSHOT"
+	EventCustom              EventType = "CUSTOM"
+)
+
+const roleAssistant = "assistant"
+
+// CustomFollowUpQuestions 推荐问题的 CUSTOM 事件名称，value 为 FollowUpQuestions
+const CustomFollowUpQuestions = "follow_up_questions"
+
+// Event AG-UI 协议的事件，只序列化对应类型需要的字段
+type Event struct {
+	Type            EventType `json:"type"`
+	Timestamp       int64     `json:"timestamp,omitempty"`
+	ThreadID        string    `json:"threadId,omitempty"`
+	RunID           string    `json:"runId,omitempty"`
+	StepName        string    `json:"stepName,omitempty"`
+	MessageID       string    `json:"messageId,omitempty"`
+	Role            string    `json:"role,omitempty"`
+	Delta           string    `json:"delta,omitempty"`
+	ToolCallID      string    `json:"toolCallId,omitempty"`
+	ToolCallName    string    `json:"toolCallName,omitempty"`
+	ParentMessageID string    `json:"parentMessageId,omitempty"`
+	Content         *string   `json:"content,omitempty"` //TOOL_CALL_RESULT 的结果，空字符串也需要输出
+	Snapshot        any       `json:"snapshot,omitempty"`
+	Message         string    `json:"message,omitempty"`
+	Code            string    `json:"code,omitempty"`
+	Name            string    `json:"name,omitempty"` //CUSTOM 事件的名称
+	Value           any       `json:"value,omitempty"`
+}
+
+// FollowUpQuestions 推荐问题，在 RUN_FINISHED 之前以 CUSTOM 事件输出。
+// t_eino 在 run_finished 之后才输出推荐问题，Converter 会将 RUN_FINISHED 延后到推荐问题之后或 Flush 时输出
+type FollowUpQuestions struct {
+	Questions []string `json:"questions"`
+}
+
+// StateSnapshot STATE_SNAPSHOT 事件中的状态，每次 ChatModel 调用结束后输出
+type StateSnapshot struct {
+	Step         int      `json:"step"`
+	ActiveSkills []string `json:"activeSkills"`
+	DynamicTools []string `json:"dynamicTools"`
+}
+
+// Converter 将一次运行的 t_eino.AgentEvent 转换为 AG-UI 事件，一次运行使用一个 Converter
+type Converter struct {
+	threadID string
+
+	runID     string
+	messageID string //当前 step 的 assistant 消息 ID，tool call 的 parentMessageId
+	textOpen  bool
+	thinking  bool
+	finished  *Event //延后输出的 RUN_FINISHED
+}
+
+func NewConverter(threadID string) *Converter {
+	return &Converter{threadID: threadID}
+}
+
+// Convert 一个 AgentEvent 可能对应零个或多个 AG-UI 事件
+func (c *Converter) Convert(e *t_eino.AgentEvent) []*Event {
+	var events []*Event
+	emit := func(event *Event) {
+		event.Timestamp = time.Now().UnixMilli()
+		events = append(events, event)
+	}
+
+	switch e.Type {
+	case t_eino.EventRunStarted:
+		c.runID = e.RunID
+		emit(&Event{Type: EventRunStarted, ThreadID: c.threadID, RunID: c.runID})
+	case t_eino.EventStepStarted:
+		c.messageID = uuid.NewString()
+		emit(&Event{Type: EventStepStarted, StepName: stepName(e.Step)})
+	case t_eino.EventReasoningDelta:
+		if !c.thinking {
+			c.thinking = true
+			emit(&Event{Type: EventThinkingStart})
+			emit(&Event{Type: EventThinkingTextStart})
+		}
+		emit(&Event{Type: EventThinkingTextContent, Delta: e.Delta})
+	case t_eino.EventTextDelta:
+		c.endThinking(emit)
+		if !c.textOpen {
+			c.textOpen = true
+			emit(&Event{Type: EventTextMessageStart, MessageID: c.messageID, Role: roleAssistant})
+		}
+		emit(&Event{Type: EventTextMessageContent, MessageID: c.messageID, Delta: e.Delta})
+	case t_eino.EventToolCallStarted:
+		c.endThinking(emit)
+		c.endText(emit)
+		emit(&Event{Type: EventToolCallStart, ToolCallID: e.ToolCallID, ToolCallName: e.ToolName, ParentMessageID: c.messageID})
+	case t_eino.EventToolCallArgsDelta:
+		emit(&Event{Type: EventToolCallArgs, ToolCallID: e.ToolCallID, Delta: e.Delta})
+	case t_eino.EventToolCallFinished:
+		emit(&Event{Type: EventToolCallEnd, ToolCallID: e.ToolCallID})
+	case t_eino.EventToolResult:
+		content := e.Result
+		emit(&Event{Type: EventToolCallResult, MessageID: uuid.NewString(), ToolCallID: e.ToolCallID, Content: &content, Role: "tool"})
+	case t_eino.EventStepFinished:
+		c.endThinking(emit)
+		c.endText(emit)
+		emit(&Event{Type: EventStepFinished, StepName: stepName(e.Step)})
+		snapshot := &StateSnapshot{Step: e.Step, ActiveSkills: []string{}, DynamicTools: []string{}}
+		if e.Session != nil {
+			snapshot.ActiveSkills = append(snapshot.ActiveSkills, e.Session.ActiveSkills...)
+			snapshot.DynamicTools = append(snapshot.DynamicTools, e.Session.DynamicTools...)
+		}
+		emit(&Event{Type: EventStateSnapshot, Snapshot: snapshot})
+	case t_eino.EventFollowUpQuestions:
+		emit(&Event{Type: EventCustom, Name: CustomFollowUpQuestions, Value: &FollowUpQuestions{Questions: e.Questions}})
+		if c.finished != nil {
+			events = append(events, c.finished)
+			c.finished = nil
+		}
+	case t_eino.EventRunFinished:
+		// 之后可能还有推荐问题，RUN_FINISHED 必须是最后一个事件
+		c.finished = &Event{Type: EventRunFinished, ThreadID: c.threadID, RunID: c.runID, Timestamp: time.Now().UnixMilli()}
+	case t_eino.EventRunError:
+		c.endThinking(emit)
+		c.endText(emit)
+		emit(&Event{Type: EventRunError, Message: e.Err.Error(), Code: errorCode(e.Err)})
+	}
+	return events
+}
+
+// Flush 运行的事件读取完毕后调用，输出延后的 RUN_FINISHED
+func (c *Converter) Flush() []*Event {
+	if c.finished == nil {
+		return nil
+	}
+	events := []*Event{c.finished}
+	c.finished = nil
+	return events
+}
+
+func (c *Converter) endText(emit func(*Event)) {
+	if c.textOpen {
+		c.textOpen = false
+		emit(&Event{Type: EventTextMessageEnd, MessageID: c.messageID})
+	}
+}
+
+func (c *Converter) endThinking(emit func(*Event)) {
+	if c.thinking {
+		c.thinking = false
+		emit(&Event{Type: EventThinkingTextEnd})
+		emit(&Event{Type: EventThinkingEnd})
+	}
+}
+
+func stepName(step int) string {
+	return fmt.Sprintf("step_%d", step)
+}
+
+// errorCode RUN_ERROR 的 code，便于前端区分错误类型
+func errorCode(err error) string {
+	var approval *t_eino.ApprovalRequiredError
+	switch {
+	case errors.As(err, &approval):
+		return "approval_required"
+	case t_eino.IsMaxStepsExceeded(err):
+		return "max_steps_exceeded"
+	case t_eino.IsToolNotFound(err):
+		return "tool_not_found"
+	case t_eino.IsToolExecutionFailed(err):
+		return "tool_execution_failed"
+	case t_eino.IsModelFailed(err):
+		return "model_failed"
+	case t_eino.IsCanceled(err):
+		return "canceled"
+	}
+	return ""
+}
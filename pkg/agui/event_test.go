@@ -0,0 +1,135 @@
+package agui
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/birdy/agent/pkg/t_eino"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/components/tool/utils"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+)
+
+// convertAll 依次转换 events，最后调用 Flush，返回 AG-UI 事件类型
+func convertAll(events []*t_eino.AgentEvent) string {
+	c := NewConverter("t1")
+	var types []string
+	for _, e := range events {
+		for _, event := range c.Convert(e) {
+			types = append(types, string(event.Type))
+		}
+	}
+	for _, event := range c.Flush() {
+		types = append(types, string(event.Type))
+	}
+	return strings.Join(types, " ")
+}
+
+func TestConverter(t *testing.T) {
+	started := &t_eino.AgentEvent{Type: t_eino.EventRunStarted, RunID: "r1"}
+	tests := []struct {
+		name   string
+		events []*t_eino.AgentEvent
+		want   string
+	}{
+		{
+			name: "text",
+			events: []*t_eino.AgentEvent{
+				started,
+				{Type: t_eino.EventStepStarted, Step: 1},
+				{Type: t_eino.EventTextDelta, Delta: "a"},
+				{Type: t_eino.EventTextDelta, Delta: "b"},
+				{Type: t_eino.EventStepFinished, Step: 1, Message: schema.AssistantMessage("ab", nil)},
+				{Type: t_eino.EventRunFinished},
+			},
+			want: "RUN_STARTED STEP_STARTED TEXT_MESSAGE_START TEXT_MESSAGE_CONTENT TEXT_MESSAGE_CONTENT TEXT_MESSAGE_END STEP_FINISHED STATE_SNAPSHOT RUN_FINISHED",
+		},
+		{
+			name: "reasoning and tool call",
+			events: []*t_eino.AgentEvent{
+				started,
+				{Type: t_eino.EventStepStarted, Step: 1},
+				{Type: t_eino.EventReasoningDelta, Delta: "think"},
+				{Type: t_eino.EventToolCallStarted, ToolCallID: "c1", ToolName: "echo"},
+				{Type: t_eino.EventToolCallArgsDelta, ToolCallID: "c1", Delta: "{}"},
+				{Type: t_eino.EventToolCallFinished, ToolCallID: "c1"},
+				{Type: t_eino.EventStepFinished, Step: 1},
+				{Type: t_eino.EventToolResult, ToolCallID: "c1", Result: "ok"},
+				{Type: t_eino.EventRunFinished},
+			},
+			want: "RUN_STARTED STEP_STARTED THINKING_START THINKING_TEXT_MESSAGE_START THINKING_TEXT_MESSAGE_CONTENT " +
+				"THINKING_TEXT_MESSAGE_END THINKING_END TOOL_CALL_START TOOL_CALL_ARGS TOOL_CALL_END STEP_FINISHED STATE_SNAPSHOT TOOL_CALL_RESULT RUN_FINISHED",
+		},
+		{
+			// t_eino 在 run_finished 之后输出推荐问题，RUN_FINISHED 需要延后
+			name: "follow up questions",
+			events: []*t_eino.AgentEvent{
+				started,
+				{Type: t_eino.EventRunFinished},
+				{Type: t_eino.EventFollowUpQuestions, Questions: []string{"q1"}},
+			},
+			want: "RUN_STARTED CUSTOM RUN_FINISHED",
+		},
+		{
+			name: "run error",
+			events: []*t_eino.AgentEvent{
+				started,
+				{Type: t_eino.EventStepStarted, Step: 1},
+				{Type: t_eino.EventTextDelta, Delta: "a"},
+				{Type: t_eino.EventRunError, Err: errors.New("boom")},
+			},
+			want: "RUN_STARTED STEP_STARTED TEXT_MESSAGE_START TEXT_MESSAGE_CONTENT TEXT_MESSAGE_END RUN_ERROR",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := convertAll(tt.events); got != tt.want {
+				t.Fatalf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConverterWithAgent(t *testing.T) {
+	ctx := context.Background()
+	// 第一步调用 echo，第二步回答，模型自己触发 callbacks
+	llm := &callbackModel{reply: func(in []*schema.Message) *schema.Message {
+		if len(in) == 1 {
+			return schema.AssistantMessage("", []schema.ToolCall{{ID: "c1", Function: schema.FunctionCall{Name: "echo", Arguments: `{"q":"x"}`}}})
+		}
+		return schema.AssistantMessage("answer", nil)
+	}}
+	echo, err := utils.InferTool("echo", "echo", func(ctx context.Context, in struct {
+		Q string `json:"q"`
+	}) (string, error) {
+		return in.Q, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := t_eino.NewAgent(ctx, &t_eino.AgentConfig{ToolCallingModel: llm, ToolsConfig: compose.ToolsNodeConfig{Tools: []tool.BaseTool{echo}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	it, err := a.StreamEvents(ctx, []*schema.Message{schema.UserMessage("hi")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer it.Close()
+	var events []*t_eino.AgentEvent
+	for {
+		e, ok := it.Next()
+		if !ok {
+			break
+		}
+		events = append(events, e)
+	}
+	want := "RUN_STARTED STEP_STARTED TOOL_CALL_START TOOL_CALL_ARGS TOOL_CALL_END STEP_FINISHED STATE_SNAPSHOT TOOL_CALL_RESULT " +
+		"STEP_STARTED TEXT_MESSAGE_START TEXT_MESSAGE_CONTENT TEXT_MESSAGE_END STEP_FINISHED STATE_SNAPSHOT RUN_FINISHED"
+	if got := convertAll(events); got != want {
+		t.Fatalf("got\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,159 @@
+package agui
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/birdy/agent/pkg/t_eino"
+	"github.com/cloudwego/eino/schema"
+)
+
+// RunAgentInput AG-UI 客户端发起一次运行的请求体，Messages 为完整的会话历史
+type RunAgentInput struct {
+	ThreadID       string          `json:"threadId"`
+	RunID          string          `json:"runId"`
+	State          any             `json:"state,omitempty"`
+	Messages       []*Message      `json:"messages"`
+	Tools          []any           `json:"tools,omitempty"`   //前端 tool，暂不支持
+	Context        []any           `json:"context,omitempty"` //暂不支持
+	ForwardedProps json.RawMessage `json:"forwardedProps,omitempty"`
+}
+
+type Message struct {
+	ID         string     `json:"id"`
+	Role       string     `json:"role"` //user、assistant、system、developer、tool
+	Content    string     `json:"content,omitempty"`
+	ToolCalls  []ToolCall `json:"toolCalls,omitempty"`
+	ToolCallID string     `json:"toolCallId,omitempty"`
+}
+
+type ToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+// HandlerConfig AG-UI 的 http.Handler 配置
+type HandlerConfig struct {
+	Agent *t_eino.Agent
+
+	// Options 可选，根据请求返回本次运行的 Option，如通过 t_eino.WithTools 注入用户相关的 tool
+	Options func(r *http.Request, input *RunAgentInput) ([]t_eino.Option, error)
+}
+
+type handler struct {
+	config *HandlerConfig
+}
+
+// NewHandler 接收 POST 的 RunAgentInput，运行 agent 并以 SSE 输出 AG-UI 事件
+func NewHandler(config *HandlerConfig) http.Handler {
+	return &handler{config: config}
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	input := &RunAgentInput{}
+	if err := json.NewDecoder(r.Body).Decode(input); err != nil {
+		http.Error(w, fmt.Sprintf("invalid RunAgentInput: %v", err), http.StatusBadRequest)
+		return
+	}
+	messages, err := ToSchemaMessages(input.Messages)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var opts []t_eino.Option
+	if h.config.Options != nil {
+		if opts, err = h.config.Options(r, input); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if input.RunID != "" {
+		opts = append(opts, t_eino.WithRunID(input.RunID))
+	}
+	iter, err := h.config.Agent.StreamEvents(r.Context(), messages, opts...)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// 客户端断开时写入失败，Close 会取消运行
+	defer iter.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+
+	converter := NewConverter(input.ThreadID)
+	for {
+		e, ok := iter.Next()
+		var events []*Event
+		if ok {
+			events = converter.Convert(e)
+		} else {
+			events = converter.Flush()
+		}
+		for _, event := range events {
+			if err = WriteSSE(w, event); err != nil {
+				return
+			}
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		if !ok {
+			return
+		}
+	}
+}
+
+// WriteSSE 以 SSE 的 data 行写入一个事件
+func WriteSSE(w http.ResponseWriter, event *Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "data: %s\n\n", data)
+	return err
+}
+
+// ToSchemaMessages 将 AG-UI 的消息转换为 eino 的消息
+func ToSchemaMessages(messages []*Message) ([]*schema.Message, error) {
+	result := make([]*schema.Message, 0, len(messages))
+	for _, m := range messages {
+		switch m.Role {
+		case "user":
+			result = append(result, schema.UserMessage(m.Content))
+		case "system", "developer":
+			result = append(result, schema.SystemMessage(m.Content))
+		case "assistant":
+			toolCalls := make([]schema.ToolCall, 0, len(m.ToolCalls))
+			for _, tc := range m.ToolCalls {
+				toolCalls = append(toolCalls, schema.ToolCall{
+					ID:       tc.ID,
+					Type:     "function",
+					Function: schema.FunctionCall{Name: tc.Function.Name, Arguments: tc.Function.Arguments},
+				})
+			}
+			if len(toolCalls) == 0 {
+				toolCalls = nil
+			}
+			result = append(result, schema.AssistantMessage(m.Content, toolCalls))
+		case "tool":
+			result = append(result, schema.ToolMessage(m.Content, m.ToolCallID))
+		default:
+			return nil, fmt.Errorf("unsupported message role %q of message %s", m.Role, m.ID)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,161 @@
+package agui
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/birdy/agent/pkg/t_eino"
+	"github.com/cloudwego/eino/callbacks"
+	"github.com/cloudwego/eino/components"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// scriptedModel 总是返回 reply
+type scriptedModel struct {
+	reply func() *schema.Message
+}
+
+func (m *scriptedModel) Generate(ctx context.Context, in []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	return m.reply(), nil
+}
+
+func (m *scriptedModel) Stream(ctx context.Context, in []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	return schema.StreamReaderFromArray([]*schema.Message{m.reply()}), nil
+}
+
+func (m *scriptedModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	return m, nil
+}
+
+// callbackModel 与真实的模型一样自己触发 callbacks
+type callbackModel struct {
+	reply func(in []*schema.Message) *schema.Message
+}
+
+func (m *callbackModel) IsCallbacksEnabled() bool {
+	return true
+}
+
+func (m *callbackModel) Generate(ctx context.Context, in []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	ctx = callbacks.EnsureRunInfo(ctx, "Callback", components.ComponentOfChatModel)
+	ctx = callbacks.OnStart(ctx, &model.CallbackInput{Messages: in})
+	msg := m.reply(in)
+	callbacks.OnEnd(ctx, &model.CallbackOutput{Message: msg})
+	return msg, nil
+}
+
+func (m *callbackModel) Stream(ctx context.Context, in []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	ctx = callbacks.EnsureRunInfo(ctx, "Callback", components.ComponentOfChatModel)
+	ctx = callbacks.OnStart(ctx, &model.CallbackInput{Messages: in})
+	_, sr := callbacks.OnEndWithStreamOutput(ctx, schema.StreamReaderFromArray([]*model.CallbackOutput{{Message: m.reply(in)}}))
+	return schema.StreamReaderWithConvert(sr, func(o *model.CallbackOutput) (*schema.Message, error) {
+		return o.Message, nil
+	}), nil
+}
+
+func (m *callbackModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	return m, nil
+}
+
+func TestHandlerFollowUp(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name     string
+		followUp *t_eino.FollowUpConfig
+		want     string
+	}{
+		{
+			name: "without follow up",
+			want: "RUN_STARTED STEP_STARTED TEXT_MESSAGE_START TEXT_MESSAGE_CONTENT TEXT_MESSAGE_END STEP_FINISHED STATE_SNAPSHOT RUN_FINISHED",
+		},
+		{
+			name: "with follow up",
+			followUp: &t_eino.FollowUpConfig{Model: &scriptedModel{reply: func() *schema.Message {
+				return schema.AssistantMessage(`{"questions":["q1"]}`, nil)
+			}}},
+			want: "RUN_STARTED STEP_STARTED TEXT_MESSAGE_START TEXT_MESSAGE_CONTENT TEXT_MESSAGE_END STEP_FINISHED STATE_SNAPSHOT CUSTOM RUN_FINISHED",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := t_eino.NewAgent(ctx, &t_eino.AgentConfig{
+				ToolCallingModel: &scriptedModel{reply: func() *schema.Message { return schema.AssistantMessage("answer", nil) }},
+				FollowUp:         tt.followUp,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			srv := httptest.NewServer(NewHandler(&HandlerConfig{Agent: a}))
+			defer srv.Close()
+			resp, err := http.Post(srv.URL, "application/json",
+				strings.NewReader(`{"threadId":"t1","runId":"r1","messages":[{"id":"m1","role":"user","content":"hi"}]}`))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			var types []string
+			scanner := bufio.NewScanner(resp.Body)
+			for scanner.Scan() {
+				data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+				if !ok {
+					continue
+				}
+				event := &Event{}
+				if err = json.Unmarshal([]byte(data), event); err != nil {
+					t.Fatal(err)
+				}
+				types = append(types, string(event.Type))
+			}
+			if got := strings.Join(types, " "); got != tt.want {
+				t.Fatalf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToSchemaMessages(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{name: "user and system", input: `[{"role":"system","content":"s"},{"role":"developer","content":"d"},{"role":"user","content":"u"}]`, want: "system system user"},
+		{
+			name:  "tool call",
+			input: `[{"role":"assistant","toolCalls":[{"id":"c1","type":"function","function":{"name":"echo","arguments":"{}"}}]},{"role":"tool","toolCallId":"c1","content":"ok"}]`,
+			want:  "assistant(echo) tool",
+		},
+		{name: "unsupported role", input: `[{"id":"m1","role":"bad"}]`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var messages []*Message
+			if err := json.Unmarshal([]byte(tt.input), &messages); err != nil {
+				t.Fatal(err)
+			}
+			msgs, err := ToSchemaMessages(messages)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got err %v, want err %v", err, tt.wantErr)
+			}
+			var roles []string
+			for _, msg := range msgs {
+				role := string(msg.Role)
+				for _, tc := range msg.ToolCalls {
+					role += "(" + tc.Function.Name + ")"
+				}
+				roles = append(roles, role)
+			}
+			if got := strings.Join(roles, " "); got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package t_eino
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+)
+
+const (
+	nodeKeyApproval = "approval"
+)
+
+// ApprovalDecisionType 对一个待审批 tool call 的处理方式
+type ApprovalDecisionType string
+
+const (
+	ApprovalApprove ApprovalDecisionType = "approve" //按原参数执行
+	ApprovalEdit    ApprovalDecisionType = "edit"    //使用 Arguments 替换原参数后执行
+	ApprovalReject  ApprovalDecisionType = "reject"  //不执行，将 Reason 作为 tool 的结果返回给大模型
+)
+
+// ApprovalDecision 人工对一个 tool call 的审批结果
+type ApprovalDecision struct {
+	Type      ApprovalDecisionType
+	Arguments string //Type 为 edit 时的新参数
+	Reason    string //Type 为 reject 时告诉大模型的原因
+}
+
+// ToolApprovalConfig 需要人工审批的 tool，命中 Tools 或 NeedApproval 返回 true 的 tool call 都需要审批
+type ToolApprovalConfig struct {
+	Tools        map[string]struct{}
+	NeedApproval func(ctx context.Context, toolCall schema.ToolCall) bool
+}
+
+// ApprovalInterruptInfo 运行暂停时的信息，ToolCalls 为等待审批的 tool call
+type ApprovalInterruptInfo struct {
+	ToolCalls []schema.ToolCall
+}
+
+// ApprovalRequiredError 运行因等待人工审批而暂停，审批后使用 Agent.Resume 继续
+type ApprovalRequiredError struct {
+	RunID     string
+	ToolCalls []schema.ToolCall
+}
+
+func (e *ApprovalRequiredError) Error() string {
+	return fmt.Sprintf("run %s is waiting for approval of %d tool calls", e.RunID, len(e.ToolCalls))
+}
+
+type approvalDecisionsKey struct{}
+
+func init() {
+	schema.RegisterName[*ApprovalInterruptInfo]("_my_eino_react_approval_interrupt_info")
+}
+
+func (c *ToolApprovalConfig) needApproval(ctx context.Context, toolCall schema.ToolCall) bool {
+	if _, ok := c.Tools[toolCall.Function.Name]; ok {
+		return true
+	}
+	return c.NeedApproval != nil && c.NeedApproval(ctx, toolCall)
+}
+
+func withApprovalDecisions(ctx context.Context, decisions map[string]*ApprovalDecision) context.Context {
+	return context.WithValue(ctx, approvalDecisionsKey{}, decisions)
+}
+
+func approvalDecisionsFromCtx(ctx context.Context) (map[string]*ApprovalDecision, bool) {
+	decisions, ok := ctx.Value(approvalDecisionsKey{}).(map[string]*ApprovalDecision)
+	return decisions, ok
+}
+
+// buildApproval 在 ChatModel 和 ToolsNode 之间插入审批节点，返回审批节点的 key
+func buildApproval(graph *compose.Graph[[]*schema.Message, *schema.Message], config *ToolApprovalConfig) (string, error) {
+	approval := func(ctx context.Context, input *schema.Message) (*schema.Message, error) {
+		if wasInterrupted, hasState, msg := compose.GetInterruptState[*schema.Message](ctx); wasInterrupted && hasState {
+			decisions, ok := approvalDecisionsFromCtx(ctx)
+			if !ok {
+				return nil, compose.StatefulInterrupt(ctx, &ApprovalInterruptInfo{ToolCalls: pendingToolCalls(ctx, config, msg)}, msg)
+			}
+			return applyApprovalDecisions(ctx, msg, pendingToolCalls(ctx, config, msg), decisions)
+		}
+
+		pending := pendingToolCalls(ctx, config, input)
+		if len(pending) == 0 {
+			return input, nil
+		}
+		return nil, compose.StatefulInterrupt(ctx, &ApprovalInterruptInfo{ToolCalls: pending}, input)
+	}
+
+	if err := graph.AddLambdaNode(nodeKeyApproval, compose.InvokableLambda(approval)); err != nil {
+		return "", err
+	}
+	if err := graph.AddEdge(nodeKeyApproval, nodeKeyTools); err != nil {
+		return "", err
+	}
+	return nodeKeyApproval, nil
+}
+
+func pendingToolCalls(ctx context.Context, config *ToolApprovalConfig, input *schema.Message) []schema.ToolCall {
+	var pending []schema.ToolCall
+	for _, toolCall := range input.ToolCalls {
+		if config.needApproval(ctx, toolCall) {
+			pending = append(pending, toolCall)
+		}
+	}
+	return pending
+}
+
+// applyApprovalDecisions 按审批结果修改 tool call 参数，被拒绝的记录到 state，由 ToolsNode 直接返回拒绝原因。
+// 没有审批结果的待审批 tool call 视为拒绝。
+func applyApprovalDecisions(ctx context.Context, msg *schema.Message, pending []schema.ToolCall, decisions map[string]*ApprovalDecision) (*schema.Message, error) {
+	output := *msg
+	output.ToolCalls = make([]schema.ToolCall, len(msg.ToolCalls))
+	copy(output.ToolCalls, msg.ToolCalls)
+
+	pendingIDs := make(map[string]struct{}, len(pending))
+	for _, toolCall := range pending {
+		pendingIDs[toolCall.ID] = struct{}{}
+	}
+	rejected := make(map[string]string)
+	for i, toolCall := range output.ToolCalls {
+		if _, ok := pendingIDs[toolCall.ID]; !ok {
+			continue
+		}
+		decision, ok := decisions[toolCall.ID]
+		if !ok || decision == nil {
+			rejected[toolCall.ID] = ""
+			continue
+		}
+		switch decision.Type {
+		case ApprovalApprove:
+		case ApprovalEdit:
+			output.ToolCalls[i].Function.Arguments = decision.Arguments
+		case ApprovalReject:
+			rejected[toolCall.ID] = decision.Reason
+		default:
+			return nil, fmt.Errorf("unknown approval decision type %s of tool call %s", decision.Type, toolCall.ID)
+		}
+	}
+	err := compose.ProcessState[*state](ctx, func(_ context.Context, s *state) error {
+		if s.RejectedToolCalls == nil {
+			s.RejectedToolCalls = make(map[string]string)
+		}
+		for id, reason := range rejected {
+			s.RejectedToolCalls[id] = reason
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &output, nil
+}
+
+// rejectedToolCall 返回被拒绝的 tool call 的结果
+func rejectedToolCall(ctx context.Context, toolCallID string) (string, bool) {
+	var (
+		reason   string
+		rejected bool
+	)
+	_ = compose.ProcessState[*state](ctx, func(_ context.Context, s *state) error {
+		reason, rejected = s.RejectedToolCalls[toolCallID]
+		return nil
+	})
+	if !rejected {
+		return "", false
+	}
+	if reason == "" {
+		return ToolCallRejectedResult, true
+	}
+	return fmt.Sprintf("%s: %s", ToolCallRejectedResult, reason), true
+}
+
+// approvalMiddleware 被拒绝的 tool call 不执行，直接返回拒绝原因
+func approvalMiddleware() compose.ToolMiddleware {
+	return compose.ToolMiddleware{
+		Invokable: func(next compose.InvokableToolEndpoint) compose.InvokableToolEndpoint {
+			return func(ctx context.Context, input *compose.ToolInput) (*compose.ToolOutput, error) {
+				if result, ok := rejectedToolCall(ctx, input.CallID); ok {
+					return &compose.ToolOutput{Result: result}, nil
+				}
+				return next(ctx, input)
+			}
+		},
+		Streamable: func(next compose.StreamableToolEndpoint) compose.StreamableToolEndpoint {
+			return func(ctx context.Context, input *compose.ToolInput) (*compose.StreamToolOutput, error) {
+				if result, ok := rejectedToolCall(ctx, input.CallID); ok {
+					return &compose.StreamToolOutput{Result: schema.StreamReaderFromArray([]string{result})}, nil
+				}
+				return next(ctx, input)
+			}
+		},
+	}
+}
+
+// approvalError 将审批中断转换为 ApprovalRequiredError
+func approvalError(runID string, err error) (*ApprovalRequiredError, bool) {
+	info, ok := compose.ExtractInterruptInfo(err)
+	if !ok {
+		return nil, false
+	}
+	for _, ic := range info.InterruptContexts {
+		if ai, ok := ic.Info.(*ApprovalInterruptInfo); ok && ic.IsRootCause {
+			return &ApprovalRequiredError{RunID: runID, ToolCalls: ai.ToolCalls}, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,89 @@
+package t_eino
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+)
+
+func TestApprovalResume(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name      string
+		decisions map[string]*ApprovalDecision
+		want      string
+	}{
+		{
+			name:      "approve",
+			decisions: map[string]*ApprovalDecision{"c1": {Type: ApprovalApprove}},
+			want:      "c1=danger got x; c2=safe got y",
+		},
+		{
+			name:      "edit",
+			decisions: map[string]*ApprovalDecision{"c1": {Type: ApprovalEdit, Arguments: `{"q":"edited"}`}},
+			want:      "c1=danger got edited; c2=safe got y",
+		},
+		{
+			name:      "reject",
+			decisions: map[string]*ApprovalDecision{"c1": {Type: ApprovalReject, Reason: "not allowed"}},
+			want:      "c1=" + ToolCallRejectedResult + ": not allowed; c2=safe got y",
+		},
+		{
+			// 没有审批结果视为拒绝
+			name: "missing decision",
+			want: "c1=" + ToolCallRejectedResult + "; c2=safe got y",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			llm := &scriptedModel{reply: func(in []*schema.Message, tools []*schema.ToolInfo) *schema.Message {
+				if in[len(in)-1].Role == schema.User {
+					return toolCallMessage(newToolCall("c1", "danger", `{"q":"x"}`), newToolCall("c2", "safe", `{"q":"y"}`))
+				}
+				return schema.AssistantMessage(toolResults(in), nil)
+			}}
+			config := &AgentConfig{
+				ToolCallingModel: llm,
+				ToolsConfig:      compose.ToolsNodeConfig{Tools: []tool.BaseTool{newTestTool("danger", "d"), newTestTool("safe", "s")}},
+				ToolApproval:     &ToolApprovalConfig{Tools: map[string]struct{}{"danger": {}}},
+				CheckPointStore:  NewInMemoryCheckPointStore(),
+			}
+			a, err := NewAgent(ctx, config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = a.Generate(ctx, []*schema.Message{schema.UserMessage("hi")})
+			var approval *ApprovalRequiredError
+			if !errors.As(err, &approval) {
+				t.Fatalf("got %v, want ApprovalRequiredError", err)
+			}
+			if len(approval.ToolCalls) != 1 || approval.ToolCalls[0].ID != "c1" {
+				t.Fatalf("got pending tool calls %v, want c1", approval.ToolCalls)
+			}
+
+			msg, err := a.Resume(ctx, approval.RunID, tt.decisions)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if msg.Content != tt.want {
+				t.Fatalf("got %q, want %q", msg.Content, tt.want)
+			}
+		})
+	}
+}
+
+func TestApprovalRequiresCheckPointStore(t *testing.T) {
+	_, err := NewAgent(context.Background(), &AgentConfig{
+		ToolCallingModel: newReplyModel("done"),
+		ToolApproval:     &ToolApprovalConfig{Tools: map[string]struct{}{"danger": {}}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "requires a checkpoint store") {
+		t.Fatalf("got %v, want checkpoint store required", err)
+	}
+}
@@ -0,0 +1,57 @@
+package t_eino
+
+import (
+	"container/list"
+	"sync"
+)
+
+// lruCache 并发安全的 LRU 缓存，超出容量时淘汰最久未使用的
+type lruCache[V any] struct {
+	lock  sync.Mutex
+	size  int
+	order *list.List //最近使用的在前
+	items map[string]*list.Element
+}
+
+type lruEntry[V any] struct {
+	key   string
+	value V
+}
+
+func newLRUCache[V any](size int) *lruCache[V] {
+	return &lruCache[V]{size: size, order: list.New(), items: make(map[string]*list.Element)}
+}
+
+func (c *lruCache[V]) get(key string) (V, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	elem, ok := c.items[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*lruEntry[V]).value, true
+}
+
+func (c *lruCache[V]) put(key string, value V) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if elem, ok := c.items[key]; ok {
+		elem.Value.(*lruEntry[V]).value = value
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.items[key] = c.order.PushFront(&lruEntry[V]{key: key, value: value})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruEntry[V]).key)
+	}
+}
+
+func (c *lruCache[V]) len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.order.Len()
+}
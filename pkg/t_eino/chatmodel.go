@@ -2,39 +2,338 @@ package t_eino
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"reflect"
+
+	"github.com/cloudwego/eino/callbacks"
+	"github.com/cloudwego/eino/components"
 	"github.com/cloudwego/eino/components/model"
 	"github.com/cloudwego/eino/schema"
 )
 
-var _ model.ToolCallingChatModel = &LearnModel{}
+const (
+	// ModelNameExtraKey ChatModel 输出的 Extra 中记录实际回答的模型名称
+	ModelNameExtraKey = "_agent_model"
+
+	defaultModelName = "primary"
+)
+
+var (
+	_ model.ToolCallingChatModel = &LearnModel{}
+	_ components.Checker         = &LearnModel{}
+)
+
+// errEmptyResponse ChatModel 没有返回任何内容，视为可重试的错误
+var errEmptyResponse = errors.New("empty response")
 
 type LearnToolFunc func([]*schema.ToolInfo)
 
+// NamedModel 带名称的模型，名称会记录在输出的 Extra 中
+type NamedModel struct {
+	Name  string
+	Model model.ToolCallingChatModel
+}
+
+// LearnModel 按顺序持有多个模型，遇到可重试的错误（超时、429、5xx、空响应）时依次使用下一个模型
 type LearnModel struct {
-	tChatModel        model.ToolCallingChatModel
-	originalChatModel model.ToolCallingChatModel
+	models      []*NamedModel //原始模型，第一个为主模型
+	routeModels []*NamedModel //只能被路由选中的模型，见 NewRoutedLearnModel
+	rules       []*RouteRule
+	tools       []*schema.ToolInfo
+	bound       bool //是否通过 WithTools 绑定过 tools
 }
 
 func NewLearnModel(ctx context.Context, llm model.ToolCallingChatModel) *LearnModel {
-	return &LearnModel{
-		tChatModel:        llm,
-		originalChatModel: llm,
-	}
+	return NewFallbackLearnModel(ctx, []*NamedModel{{Name: defaultModelName, Model: llm}})
+}
+
+// NewFallbackLearnModel models 按顺序尝试，第一个为主模型
+func NewFallbackLearnModel(_ context.Context, models []*NamedModel) *LearnModel {
+	return &LearnModel{models: models}
+}
+
+func (l *LearnModel) GetType() string {
+	return "LearnModel"
+}
+
+// IsCallbacksEnabled LearnModel 自己触发 callbacks，内部的模型在去掉 callbacks 的 ctx 中调用，
+// 每次调用只报告一次，回退时失败的尝试不会报告
+func (l *LearnModel) IsCallbacksEnabled() bool {
+	return true
 }
 
 func (l *LearnModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
-	return l.tChatModel.Generate(ctx, input, opts...)
+	ctx = callbacks.EnsureRunInfo(ctx, l.GetType(), components.ComponentOfChatModel)
+	ctx = callbacks.OnStart(ctx, &model.CallbackInput{Messages: input})
+	msg, err := l.generate(ctx, input, opts...)
+	if err != nil {
+		callbacks.OnError(ctx, err)
+		return nil, err
+	}
+	callbacks.OnEnd(ctx, &model.CallbackOutput{Message: msg})
+	return msg, nil
 }
 
 func (l *LearnModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
-	return l.tChatModel.Stream(ctx, input, opts...)
+	ctx = callbacks.EnsureRunInfo(ctx, l.GetType(), components.ComponentOfChatModel)
+	ctx = callbacks.OnStart(ctx, &model.CallbackInput{Messages: input})
+	sr, err := l.stream(ctx, input, opts...)
+	if err != nil {
+		callbacks.OnError(ctx, err)
+		return nil, err
+	}
+	_, out := callbacks.OnEndWithStreamOutput(ctx, schema.StreamReaderWithConvert(sr, func(msg *schema.Message) (*model.CallbackOutput, error) {
+		return &model.CallbackOutput{Message: msg}, nil
+	}))
+	return schema.StreamReaderWithConvert(out, func(o *model.CallbackOutput) (*schema.Message, error) {
+		return o.Message, nil
+	}), nil
 }
 
-func (l *LearnModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
-	newModel, err := l.originalChatModel.WithTools(tools)
+// withoutCallbacks 去掉 ctx 中本次运行的 callback handler，用于调用内部的模型
+func withoutCallbacks(ctx context.Context) context.Context {
+	return callbacks.InitCallbacks(ctx, nil)
+}
+
+func (l *LearnModel) generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	models, err := l.getChatModels(ctx, l.route(ctx, input))
+	if err != nil {
+		return nil, newModelError(ctx, err)
+	}
+	var errs []error
+	for i, m := range models {
+		msg, err := m.Model.Generate(withoutCallbacks(ctx), input, opts...)
+		// 最后一个模型的空响应原样返回
+		if err == nil && isEmptyMessage(msg) && i < len(models)-1 {
+			err = errEmptyResponse
+		}
+		if err == nil {
+			setModelName(msg, m.Name)
+			return msg, nil
+		}
+		errs = append(errs, fmt.Errorf("model %s: %w", m.Name, err))
+		if !isRetryableModelError(ctx, err) {
+			break
+		}
+	}
+	return nil, newModelError(ctx, joinModelErrors(errs))
+}
+
+func (l *LearnModel) stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	models, err := l.getChatModels(ctx, l.route(ctx, input))
+	if err != nil {
+		return nil, newModelError(ctx, err)
+	}
+	var (
+		errs []error
+		sr   *schema.StreamReader[*schema.Message]
+	)
+	for i, m := range models {
+		sr, err = l.streamFirst(ctx, m, i == len(models)-1, input, opts...)
+		if err == nil {
+			break
+		}
+		errs = append(errs, fmt.Errorf("model %s: %w", m.Name, err))
+		if !isRetryableModelError(ctx, err) {
+			break
+		}
+	}
+	if sr == nil {
+		return nil, newModelError(ctx, joinModelErrors(errs))
+	}
+	// 流中途的错误同样包装为 AgentError
+	step := currentStep(ctx)
+	return schema.StreamReaderWithConvert(sr, func(msg *schema.Message) (*schema.Message, error) {
+		return msg, nil
+	}, schema.WithErrWrapper(func(err error) error {
+		return &AgentError{Kind: errorKind(err, ErrModel), Step: step, Err: err}
+	})), nil
+}
+
+// streamFirst 读到第一个片段才算成功，之后的错误不再切换模型，避免已输出的内容重复。
+// last 为 true 时空的流原样返回。
+func (l *LearnModel) streamFirst(ctx context.Context, m *NamedModel, last bool, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	sr, err := m.Model.Stream(withoutCallbacks(ctx), input, opts...)
+	if err != nil {
+		return nil, err
+	}
+	first, err := sr.Recv()
+	if err == io.EOF {
+		sr.Close()
+		if last {
+			return schema.StreamReaderFromArray([]*schema.Message{}), nil
+		}
+		return nil, errEmptyResponse
+	}
 	if err != nil {
+		sr.Close()
 		return nil, err
 	}
-	l.tChatModel = newModel
-	return l, err
+	first = withModelName(first, m.Name)
+
+	out, w := schema.Pipe[*schema.Message](1)
+	go func() {
+		defer sr.Close()
+		defer w.Close()
+		if w.Send(first, nil) {
+			return
+		}
+		for {
+			msg, err := sr.Recv()
+			if err == io.EOF {
+				return
+			}
+			if w.Send(msg, err) || err != nil {
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// getChatModels 在图内运行时按 ToolSession 当前的 alive tools 重新绑定每个模型，
+// 这样 special_get_tool 获取的 tool 在下一次 ChatModel 调用时即可见
+func (l *LearnModel) getChatModels(ctx context.Context, chain []*NamedModel) ([]*NamedModel, error) {
+	tools, bind := l.tools, l.bound
+	if s, ok := GetToolSession(ctx); ok {
+		infos, err := s.ToolInfos(ctx)
+		if err != nil {
+			return nil, err
+		}
+		tools, bind = infos, true
+	}
+	// 真实的模型不能绑定空的 tool 列表，没有 tool 时直接使用原始模型
+	if !bind || len(tools) == 0 {
+		return chain, nil
+	}
+	models := make([]*NamedModel, 0, len(chain))
+	for _, m := range chain {
+		bound, err := m.Model.WithTools(tools)
+		if err != nil {
+			return nil, fmt.Errorf("model %s bind tools fail: %w", m.Name, err)
+		}
+		models = append(models, &NamedModel{Name: m.Name, Model: bound})
+	}
+	return models, nil
+}
+
+func (l *LearnModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	// 先检查每个模型都能绑定，tools 为空时调用时也不会绑定
+	if len(tools) > 0 {
+		for _, m := range append(append([]*NamedModel(nil), l.models...), l.routeModels...) {
+			if _, err := m.Model.WithTools(tools); err != nil {
+				return nil, err
+			}
+		}
+	}
+	// 返回新的实例，不修改共享的 LearnModel，避免并发运行之间互相覆盖
+	return &LearnModel{
+		models:      l.models,
+		routeModels: l.routeModels,
+		rules:       l.rules,
+		tools:       tools,
+		bound:       true,
+	}, nil
+}
+
+// joinModelErrors 只有一个模型失败时返回原始错误
+func joinModelErrors(errs []error) error {
+	if len(errs) == 1 {
+		return errors.Unwrap(errs[0])
+	}
+	return errors.Join(errs...)
+}
+
+func isEmptyMessage(msg *schema.Message) bool {
+	return msg == nil || (msg.Content == "" && msg.ReasoningContent == "" && len(msg.ToolCalls) == 0 &&
+		len(msg.MultiContent) == 0 && len(msg.AssistantGenMultiContent) == 0)
+}
+
+func setModelName(msg *schema.Message, name string) {
+	if msg.Extra == nil {
+		msg.Extra = make(map[string]any)
+	}
+	msg.Extra[ModelNameExtraKey] = name
+}
+
+// withModelName 流的片段可能被上游共享，复制后再写入
+func withModelName(msg *schema.Message, name string) *schema.Message {
+	if msg == nil {
+		msg = &schema.Message{Role: schema.Assistant}
+	}
+	cp := *msg
+	cp.Extra = make(map[string]any, len(msg.Extra)+1)
+	for k, v := range msg.Extra {
+		cp.Extra[k] = v
+	}
+	cp.Extra[ModelNameExtraKey] = name
+	return &cp
+}
+
+// GetModelName 获取 ChatModel 输出中记录的模型名称
+func GetModelName(msg *schema.Message) string {
+	if msg == nil {
+		return ""
+	}
+	name, _ := msg.Extra[ModelNameExtraKey].(string)
+	return name
+}
+
+// isRetryableModelError 超时、429、5xx 和空响应可以换下一个模型重试，运行本身被取消时不重试
+func isRetryableModelError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if errors.Is(err, errEmptyResponse) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	code := httpStatusCode(err)
+	return code == http.StatusTooManyRequests || code >= http.StatusInternalServerError
+}
+
+// httpStatusCode 各 provider 的错误类型不同，但都带有 HTTPStatusCode 或 StatusCode 字段
+func httpStatusCode(err error) int {
+	for _, e := range unwrapAll(err) {
+		v := reflect.ValueOf(e)
+		for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				break
+			}
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			continue
+		}
+		for _, name := range []string{"HTTPStatusCode", "StatusCode"} {
+			if f := v.FieldByName(name); f.IsValid() && f.CanInt() && f.Int() > 0 {
+				return int(f.Int())
+			}
+		}
+	}
+	return 0
+}
+
+// unwrapAll 展开错误链，包括 errors.Join
+func unwrapAll(err error) []error {
+	if err == nil {
+		return nil
+	}
+	errs := []error{err}
+	switch e := err.(type) {
+	case interface{ Unwrap() error }:
+		errs = append(errs, unwrapAll(e.Unwrap())...)
+	case interface{ Unwrap() []error }:
+		for _, inner := range e.Unwrap() {
+			errs = append(errs, unwrapAll(inner)...)
+		}
+	}
+	return errs
 }
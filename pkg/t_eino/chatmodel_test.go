@@ -0,0 +1,261 @@
+package t_eino
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+)
+
+// httpStatusError 模拟 provider 返回的带 HTTPStatusCode 字段的错误
+type httpStatusError struct {
+	HTTPStatusCode int
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("status %d", e.HTTPStatusCode)
+}
+
+// failingModel err 为空时返回空响应
+type failingModel struct {
+	err   error
+	calls int
+}
+
+func (m *failingModel) Generate(ctx context.Context, in []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	m.calls++
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &schema.Message{Role: schema.Assistant}, nil
+}
+
+func (m *failingModel) Stream(ctx context.Context, in []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	m.calls++
+	if m.err != nil {
+		return nil, m.err
+	}
+	return schema.StreamReaderFromArray([]*schema.Message{}), nil
+}
+
+func (m *failingModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	return m, nil
+}
+
+func TestFallbackLearnModel(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name      string
+		models    func() []*NamedModel
+		wantModel string //回答的模型，为空时期望失败
+		wantCalls []int  //每个 failingModel 被调用的次数，按出现顺序
+	}{
+		{
+			name: "429 and empty response fall back",
+			models: func() []*NamedModel {
+				return []*NamedModel{
+					{Name: "a", Model: &failingModel{err: &httpStatusError{HTTPStatusCode: 429}}},
+					{Name: "b", Model: &failingModel{}},
+					{Name: "c", Model: newReplyModel("ok")},
+				}
+			},
+			wantModel: "c",
+			wantCalls: []int{1, 1},
+		},
+		{
+			name: "5xx and timeout fall back",
+			models: func() []*NamedModel {
+				return []*NamedModel{
+					{Name: "a", Model: &failingModel{err: &httpStatusError{HTTPStatusCode: 503}}},
+					{Name: "b", Model: &failingModel{err: fmt.Errorf("request: %w", context.DeadlineExceeded)}},
+					{Name: "c", Model: newReplyModel("ok")},
+				}
+			},
+			wantModel: "c",
+			wantCalls: []int{1, 1},
+		},
+		{
+			name: "non retryable error stops the chain",
+			models: func() []*NamedModel {
+				return []*NamedModel{
+					{Name: "a", Model: &failingModel{err: &httpStatusError{HTTPStatusCode: 400}}},
+					{Name: "b", Model: &failingModel{err: &httpStatusError{HTTPStatusCode: 500}}},
+				}
+			},
+			wantCalls: []int{1, 0},
+		},
+		{
+			name: "all models fail",
+			models: func() []*NamedModel {
+				return []*NamedModel{
+					{Name: "a", Model: &failingModel{err: &httpStatusError{HTTPStatusCode: 503}}},
+					{Name: "b", Model: &failingModel{err: &httpStatusError{HTTPStatusCode: 502}}},
+				}
+			},
+			wantCalls: []int{1, 1},
+		},
+		{
+			// 最后一个模型的空响应原样返回
+			name: "empty response of the last model",
+			models: func() []*NamedModel {
+				return []*NamedModel{{Name: "a", Model: &failingModel{}}}
+			},
+			wantModel: "a",
+			wantCalls: []int{1},
+		},
+	}
+	for _, tt := range tests {
+		for _, stream := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s/stream=%v", tt.name, stream), func(t *testing.T) {
+				models := tt.models()
+				lm := NewFallbackLearnModel(ctx, models)
+				input := []*schema.Message{schema.UserMessage("hi")}
+
+				var (
+					msg *schema.Message
+					err error
+				)
+				if stream {
+					var sr *schema.StreamReader[*schema.Message]
+					if sr, err = lm.Stream(ctx, input); err == nil {
+						msgs, recvErr := readAll(sr)
+						if recvErr != nil {
+							t.Fatal(recvErr)
+						}
+						// 空的流原样返回，没有片段记录模型名称
+						if len(msgs) > 0 {
+							msg = msgs[0]
+						}
+					}
+				} else {
+					msg, err = lm.Generate(ctx, input)
+				}
+
+				if tt.wantModel == "" {
+					if !IsModelFailed(err) {
+						t.Fatalf("got %v, want model failed error", err)
+					}
+				} else {
+					if err != nil {
+						t.Fatal(err)
+					}
+					if got := GetModelName(msg); msg != nil && got != tt.wantModel {
+						t.Fatalf("got model %q, want %q", got, tt.wantModel)
+					}
+				}
+				var calls []int
+				for _, m := range models {
+					if fm, ok := m.Model.(*failingModel); ok {
+						calls = append(calls, fm.calls)
+					}
+				}
+				if fmt.Sprint(calls) != fmt.Sprint(tt.wantCalls) {
+					t.Fatalf("got calls %v, want %v", calls, tt.wantCalls)
+				}
+			})
+		}
+	}
+}
+
+func TestLearnModelCallbacks(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		call func(ctx context.Context, l *LearnModel) (*schema.Message, error)
+	}{
+		{
+			name: "generate",
+			call: func(ctx context.Context, l *LearnModel) (*schema.Message, error) {
+				return l.Generate(ctx, []*schema.Message{schema.UserMessage("hi")})
+			},
+		},
+		{
+			name: "stream",
+			call: func(ctx context.Context, l *LearnModel) (*schema.Message, error) {
+				sr, err := l.Stream(ctx, []*schema.Message{schema.UserMessage("hi")})
+				if err != nil {
+					return nil, err
+				}
+				msgs, err := readAll(sr)
+				if err != nil {
+					return nil, err
+				}
+				return schema.ConcatMessages(msgs)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 内部的模型自己触发 callbacks，回退后也只报告 LearnModel 的一次调用
+			l := NewFallbackLearnModel(ctx, []*NamedModel{
+				{Name: "primary", Model: &failingModel{err: &httpStatusError{HTTPStatusCode: 429}}},
+				{Name: "backup", Model: &callbackModel{scriptedModel: newReplyModel("ok")}},
+			})
+			counter := &modelCallbackCounter{}
+			msg, err := tt.call(counter.context(ctx), l)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if msg.Content != "ok" || counter.start != 1 || counter.end != 1 {
+				t.Fatalf("got %q with callbacks %+v, want ok with one start and one end", msg.Content, *counter)
+			}
+		})
+	}
+}
+
+func TestAgentWithoutTools(t *testing.T) {
+	ctx := context.Background()
+	// 真实的 provider 绑定空的 tool 列表时返回 "no tools to bind"
+	var gotTools []any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		if tools, ok := payload["tools"].([]any); ok {
+			gotTools = append(gotTools, tools...)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"1","object":"chat.completion","created":1,"model":"m","choices":[{"index":0,"message":{"role":"assistant","content":"hi"},"finish_reason":"stop"}]}`))
+	}))
+	defer srv.Close()
+	llm, err := NewChatModel(ctx, &ModelConfig{Provider: ModelProviderOpenAI, Model: "m", APIKey: "k", BaseURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := NewAgent(ctx, &AgentConfig{ToolCallingModel: llm})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := a.Generate(ctx, []*schema.Message{schema.UserMessage("hello")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Content != "hi" || len(gotTools) != 0 {
+		t.Fatalf("got %q with tools %v, want hi without tools", msg.Content, gotTools)
+	}
+}
+
+func TestAgentFallbackModels(t *testing.T) {
+	ctx := context.Background()
+	a, err := NewAgent(ctx, &AgentConfig{
+		ToolCallingModel: &failingModel{err: &httpStatusError{HTTPStatusCode: 500}},
+		FallbackModels:   []*NamedModel{{Name: "backup", Model: newReplyModel("ok")}},
+		ToolsConfig:      compose.ToolsNodeConfig{Tools: []tool.BaseTool{newTestTool("t1", "d")}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := a.Generate(ctx, []*schema.Message{schema.UserMessage("hi")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if GetModelName(msg) != "backup" || msg.Content != "ok" {
+		t.Fatalf("got %q from %q, want ok from backup", msg.Content, GetModelName(msg))
+	}
+}
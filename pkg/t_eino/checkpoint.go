@@ -0,0 +1,291 @@
+package t_eino
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+)
+
+const (
+	nodeKeyResume = "resume"
+
+	runSnapshotSuffix = ".snapshot"
+)
+
+// CheckPointStore 保存运行状态，除了 compose 在中断时写入的 checkpoint（key 为 run ID），
+// 每完成一步还会写入一份运行快照（key 为 run ID + ".snapshot"），用于进程崩溃后从最近完成的一步继续。
+// 运行成功结束后这两项都会被删除，失败或中断的运行保留到成功恢复为止。
+type CheckPointStore interface {
+	compose.CheckPointStore
+	// Delete 删除 checkpoint，不存在时不返回错误
+	Delete(ctx context.Context, checkPointID string) error
+}
+
+// ToolSessionState ToolSession 中需要随运行状态保存的部分，额外的 tool 本身不保存，恢复时需重新通过 WithTools 注入
+type ToolSessionState struct {
+	DynamicTools []string //动态获取的 tool，按最近使用排序
+	ActiveSkills []string //已激活的 skill，按激活顺序
+}
+
+// runSnapshot 运行快照
+type runSnapshot struct {
+	State       *state
+	Interrupted bool //因审批等中断暂停，需从 compose 的 checkpoint 继续
+}
+
+// RunError 设置了 CheckPointStore 时运行失败返回的错误，可使用 RunID 调用 Agent.Resume 从最近完成的一步继续
+type RunError struct {
+	RunID string
+	Err   error
+}
+
+func (e *RunError) Error() string {
+	return fmt.Sprintf("run %s fail: %v", e.RunID, e.Err)
+}
+
+func (e *RunError) Unwrap() error {
+	return e.Err
+}
+
+type runInfoKey struct{}
+
+// runInfo 单次运行的信息，store 为空时不保存运行状态
+type runInfo struct {
+	id       string
+	store    CheckPointStore
+	restored *state            //从快照恢复的 state
+	step     atomic.Int64      //ChatModel 已被调用的次数，运行结束后用于错误信息
+	messages []*schema.Message //配置了 FollowUp 时记录 state 中最新的消息
+}
+
+func withRunInfo(ctx context.Context, info *runInfo) context.Context {
+	return context.WithValue(ctx, runInfoKey{}, info)
+}
+
+func runInfoFromCtx(ctx context.Context) *runInfo {
+	info, _ := ctx.Value(runInfoKey{}).(*runInfo)
+	return info
+}
+
+// GetRunID 获取当前运行的 ID
+func GetRunID(ctx context.Context) string {
+	if info := runInfoFromCtx(ctx); info != nil {
+		return info.id
+	}
+	return ""
+}
+
+// State 返回需要随运行状态保存的部分
+func (s *ToolSession) State() *ToolSessionState {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	st := &ToolSessionState{
+		DynamicTools: append([]string(nil), s.dynamicTools...),
+		ActiveSkills: make([]string, 0, len(s.activeSkills)),
+	}
+	for _, skill := range s.activeSkills {
+		st.ActiveSkills = append(st.ActiveSkills, skill.Name)
+	}
+	return st
+}
+
+// Restore 按保存的状态重新激活 skill、获取动态 tool
+func (s *ToolSession) Restore(ctx context.Context, st *ToolSessionState) error {
+	if st == nil {
+		return nil
+	}
+	for _, name := range st.ActiveSkills {
+		if _, err := s.UseSkill(ctx, name); err != nil {
+			return err
+		}
+	}
+	for _, name := range st.DynamicTools {
+		_, _, ok, err := s.loadTool(ctx, name)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("restore tool %s fail: tool is not exist, inject it with WithTools", name)
+		}
+	}
+	return nil
+}
+
+// saveRunSnapshot 在节点的 pre handler 中调用，保存最近完成的一步
+func saveRunSnapshot(ctx context.Context, st *state) error {
+	info := runInfoFromCtx(ctx)
+	if info == nil || info.store == nil {
+		return nil
+	}
+	if session := st.getToolSession(ctx); session != nil {
+		st.Session = session.State()
+	}
+	return setRunSnapshot(ctx, info.store, info.id, &runSnapshot{State: st})
+}
+
+func setRunSnapshot(ctx context.Context, store CheckPointStore, runID string, snapshot *runSnapshot) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("marshal run snapshot fail: %w", err)
+	}
+	return store.Set(ctx, runID+runSnapshotSuffix, data)
+}
+
+func getRunSnapshot(ctx context.Context, store CheckPointStore, runID string) (*runSnapshot, bool, error) {
+	data, ok, err := store.Get(ctx, runID+runSnapshotSuffix)
+	if err != nil || !ok {
+		return nil, ok, err
+	}
+	snapshot := &runSnapshot{}
+	if err = json.Unmarshal(data, snapshot); err != nil {
+		return nil, false, fmt.Errorf("unmarshal run snapshot fail: %w", err)
+	}
+	return snapshot, true, nil
+}
+
+// deleteRun 删除运行的 checkpoint 和快照
+func deleteRun(ctx context.Context, store CheckPointStore, runID string) error {
+	return errors.Join(store.Delete(ctx, runID), store.Delete(ctx, runID+runSnapshotSuffix))
+}
+
+// buildResume 从快照恢复时，若最近完成的一步是 ChatModel 返回了 tool call，则跳过 ChatModel 直接执行 tool
+func buildResume(graph *compose.Graph[[]*schema.Message, *schema.Message], toolCallTarget string) error {
+	resume := func(ctx context.Context, _ []*schema.Message) (msg *schema.Message, err error) {
+		err = compose.ProcessState[*state](ctx, func(_ context.Context, s *state) error {
+			msg = s.Messages[len(s.Messages)-1]
+			// 由 ToolsNode 的 pre handler 重新加入
+			s.Messages = s.Messages[:len(s.Messages)-1]
+			return nil
+		})
+		return msg, err
+	}
+	if err := graph.AddLambdaNode(nodeKeyResume, compose.InvokableLambda(resume)); err != nil {
+		return err
+	}
+	if err := graph.AddEdge(nodeKeyResume, toolCallTarget); err != nil {
+		return err
+	}
+
+	return graph.AddBranch(compose.START, compose.NewGraphBranch(func(ctx context.Context, input []*schema.Message) (endNode string, err error) {
+		endNode = nodeKeyModel
+		if len(input) > 0 {
+			return endNode, nil
+		}
+		err = compose.ProcessState[*state](ctx, func(_ context.Context, s *state) error {
+			if n := len(s.Messages); n > 0 && s.Messages[n-1].Role == schema.Assistant && len(s.Messages[n-1].ToolCalls) > 0 {
+				endNode = nodeKeyResume
+			}
+			return nil
+		})
+		return endNode, err
+	}, map[string]bool{nodeKeyModel: true, nodeKeyResume: true}))
+}
+
+// inMemoryCheckPointStore 进程内的 CheckPointStore，进程退出后丢失
+type inMemoryCheckPointStore struct {
+	lock sync.RWMutex
+	m    map[string][]byte
+}
+
+func NewInMemoryCheckPointStore() CheckPointStore {
+	return &inMemoryCheckPointStore{m: make(map[string][]byte)}
+}
+
+func (s *inMemoryCheckPointStore) Get(_ context.Context, checkPointID string) ([]byte, bool, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	data, ok := s.m[checkPointID]
+	return data, ok, nil
+}
+
+func (s *inMemoryCheckPointStore) Set(_ context.Context, checkPointID string, checkPoint []byte) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.m[checkPointID] = checkPoint
+	return nil
+}
+
+func (s *inMemoryCheckPointStore) Delete(_ context.Context, checkPointID string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.m, checkPointID)
+	return nil
+}
+
+// fileCheckPointStore 本地文件 CheckPointStore，每个 checkpoint 一个文件
+type fileCheckPointStore struct {
+	dir string
+}
+
+// NewFileCheckPointStore checkpoint 保存在 dir 目录下，目录不存在时会被创建
+func NewFileCheckPointStore(dir string) (CheckPointStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &fileCheckPointStore{dir: dir}, nil
+}
+
+func (s *fileCheckPointStore) path(checkPointID string) (string, error) {
+	name := url.PathEscape(checkPointID)
+	if name == "" || name == "." || name == ".." {
+		return "", fmt.Errorf("invalid checkpoint id %q", checkPointID)
+	}
+	return filepath.Join(s.dir, name), nil
+}
+
+func (s *fileCheckPointStore) Get(_ context.Context, checkPointID string) ([]byte, bool, error) {
+	path, err := s.path(checkPointID)
+	if err != nil {
+		return nil, false, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return data, true, nil
+}
+
+// Set 先写临时文件再重命名，避免进程崩溃时留下不完整的 checkpoint
+func (s *fileCheckPointStore) Set(_ context.Context, checkPointID string, checkPoint []byte) error {
+	path, err := s.path(checkPointID)
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(s.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err = f.Write(checkPoint); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+func (s *fileCheckPointStore) Delete(_ context.Context, checkPointID string) error {
+	path, err := s.path(checkPointID)
+	if err != nil {
+		return err
+	}
+	if err = os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package t_eino
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+)
+
+func TestResumeAfterFailure(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name  string
+		store func(t *testing.T) CheckPointStore
+	}{
+		{name: "in memory", store: func(t *testing.T) CheckPointStore { return NewInMemoryCheckPointStore() }},
+		{
+			name: "file",
+			store: func(t *testing.T) CheckPointStore {
+				store, err := NewFileCheckPointStore(t.TempDir())
+				if err != nil {
+					t.Fatal(err)
+				}
+				return store
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 获取 extra 后调用，第一次运行时 extra 失败
+			var steps []string
+			llm := &scriptedModel{reply: func(in []*schema.Message, tools []*schema.ToolInfo) *schema.Message {
+				last := in[len(in)-1]
+				steps = append(steps, string(last.Role))
+				switch {
+				case last.Role == schema.User:
+					return toolCallMessage(newToolCall("g", SpecialGetToolToolName, `{"name":"extra"}`))
+				case last.ToolCallID == "g":
+					return toolCallMessage(newToolCall("e", "extra", `{"q":"x"}`))
+				}
+				return schema.AssistantMessage(last.Content, nil)
+			}}
+			fail := true
+			extra := newFuncTool("extra", func(ctx context.Context, q string) (string, error) {
+				if fail {
+					return "", errors.New("boom")
+				}
+				return "extra ok in " + GetRunID(ctx), nil
+			})
+			a, err := NewAgent(ctx, &AgentConfig{ToolCallingModel: llm, CheckPointStore: tt.store(t)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			o, err := WithTools(ctx, extra)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = a.Generate(ctx, []*schema.Message{schema.UserMessage("hi")}, o, WithRunID("run/1"))
+			var runErr *RunError
+			if !errors.As(err, &runErr) || runErr.RunID != "run/1" || !IsToolExecutionFailed(err) {
+				t.Fatalf("got %v, want RunError of run/1", err)
+			}
+
+			// 从最近完成的一步继续，动态获取的 extra 仍然可用
+			fail = false
+			steps = nil
+			msg, err := a.Resume(ctx, "run/1", nil, o)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if msg.Content != "extra ok in run/1" {
+				t.Fatalf("got %q", msg.Content)
+			}
+			if len(steps) != 1 || steps[0] != string(schema.Tool) {
+				t.Fatalf("got steps %v after resume, want only the last step", steps)
+			}
+
+			// 成功结束的运行不再保存状态
+			if _, err = a.Resume(ctx, "run/1", nil); err == nil {
+				t.Fatal("got nil error after the run finished")
+			}
+		})
+	}
+}
+
+func TestResumeInvalid(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name   string
+		config *AgentConfig
+	}{
+		{name: "no checkpoint store", config: &AgentConfig{ToolCallingModel: newReplyModel("done")}},
+		{name: "unknown run", config: &AgentConfig{ToolCallingModel: newReplyModel("done"), CheckPointStore: NewInMemoryCheckPointStore()}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := NewAgent(ctx, tt.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err = a.Resume(ctx, "nope", nil); err == nil {
+				t.Fatal("got nil error")
+			}
+		})
+	}
+}
+
+func TestCheckPointReleased(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		// run 运行结束后返回运行的 ID
+		run func(t *testing.T, a *Agent) string
+	}{
+		{
+			name: "finished run",
+			run: func(t *testing.T, a *Agent) string {
+				if _, err := a.Generate(ctx, []*schema.Message{schema.UserMessage("safe")}, WithRunID("r1")); err != nil {
+					t.Fatal(err)
+				}
+				return "r1"
+			},
+		},
+		{
+			name: "approved run",
+			run: func(t *testing.T, a *Agent) string {
+				_, err := a.Generate(ctx, []*schema.Message{schema.UserMessage("danger")})
+				var approval *ApprovalRequiredError
+				if !errors.As(err, &approval) {
+					t.Fatalf("got %v, want ApprovalRequiredError", err)
+				}
+				if _, err = a.Resume(ctx, approval.RunID, map[string]*ApprovalDecision{"c": {Type: ApprovalApprove}}); err != nil {
+					t.Fatal(err)
+				}
+				return approval.RunID
+			},
+		},
+		{
+			name: "streamed run",
+			run: func(t *testing.T, a *Agent) string {
+				it, err := a.Stream(ctx, []*schema.Message{schema.UserMessage("safe")}, WithRunID("r1"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer it.Close()
+				for {
+					chunk, ok := it.Next()
+					if !ok {
+						break
+					}
+					if chunk.Done && chunk.Err != nil {
+						t.Fatal(chunk.Err)
+					}
+					discardStreamChunk(chunk)
+				}
+				return "r1"
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 调用 user message 指定的 tool 后结束
+			llm := &scriptedModel{reply: func(in []*schema.Message, tools []*schema.ToolInfo) *schema.Message {
+				if last := in[len(in)-1]; last.Role == schema.User {
+					return toolCallMessage(newToolCall("c", last.Content, `{"q":"x"}`))
+				}
+				return schema.AssistantMessage(toolResults(in), nil)
+			}}
+			store := NewInMemoryCheckPointStore().(*inMemoryCheckPointStore)
+			a, err := NewAgent(ctx, &AgentConfig{
+				ToolCallingModel: llm,
+				ToolsConfig:      compose.ToolsNodeConfig{Tools: []tool.BaseTool{newTestTool("danger", "d"), newTestTool("safe", "s")}},
+				ToolApproval:     &ToolApprovalConfig{Tools: map[string]struct{}{"danger": {}}},
+				CheckPointStore:  store,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			runID := tt.run(t, a)
+			if len(store.m) != 0 {
+				t.Fatalf("got %d checkpoints left after run %s finished", len(store.m), runID)
+			}
+		})
+	}
+}
@@ -0,0 +1,440 @@
+package t_eino
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/components/tool/utils"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/flow/agent"
+	"github.com/cloudwego/eino/schema"
+)
+
+const (
+	AddSkillToolName = "add_skill"
+
+	defaultCompressMaxStep = 6
+)
+
+// CompressedSkill add_skill 的结果，以 json 保存在 tool message 中，重构上下文时从中还原 skill
+type CompressedSkill struct {
+	Name         string   `json:"name"`
+	Description  string   `json:"description"`
+	Instructions string   `json:"instructions"`
+	SubSkills    []string `json:"sub_skills,omitempty"` //被隐藏到该 skill 之下的 skill
+}
+
+// SkillNameRewriter name 与已有 skill 重复时返回新的名称，exists 判断名称是否已被使用
+type SkillNameRewriter func(name string, exists func(name string) bool) string
+
+// CompressAgentConfig 压缩 agent 的配置
+type CompressAgentConfig struct {
+	// Agent 主 react 的配置，Skills 为预设的 skill
+	Agent *AgentConfig
+	// CompressModel 可选，压缩者使用的模型，默认使用 Agent.ToolCallingModel
+	CompressModel model.ToolCallingChatModel
+	// CompressPrompt 可选，压缩者的 system prompt（总结规则、skill 模版等），默认 CompressSystemPrompt
+	CompressPrompt string
+	// CompressMaxStep 可选，压缩者的 MaxStep，默认 6
+	CompressMaxStep int
+	// HideSubSkills add_skill 是否可以将引用到的已有 skill 隐藏到新 skill 之下，只有新 skill 被激活时才可见
+	HideSubSkills bool
+	// SkillNameRewriter 可选，add_skill 给出的名称重复时重写，默认追加 _2、_3 ...
+	SkillNameRewriter SkillNameRewriter
+}
+
+// CompressAgent 通过压缩上下文承载超长对话的 agent，本身无状态，调用方每次传入完整的上下文：
+//   - 运行前重构上下文：以 add_skill 的结果结尾的一段上下文（子上下文）被替换为 skill，只保留 system message、固定的消息（PinMessage）和最后一次 add_skill 之后的消息；
+//   - 运行主 react，压缩得到的 skill 通过 WithSkills 注入；
+//   - 运行压缩者，它只绑定了 add_skill 且 tool choice 被强制为 add_skill，将本段上下文总结为 skill，除此之外的输出都被丢弃。
+type CompressAgent struct {
+	agent         *Agent
+	compressor    *Agent
+	hideSubSkills bool
+	nameRewriter  SkillNameRewriter
+	presetSkills  map[string]struct{} //预设 skill 的名称，包含子 skill
+}
+
+// CompressOutput 一次运行的结果
+type CompressOutput struct {
+	// Message 主 react 的输出
+	Message *schema.Message
+	// Messages 需要追加到上下文中的消息：主 react 本次运行中的 tool call 和 tool 结果、Message，以及压缩时 add_skill 的调用和结果。
+	// 主 react 配置了 MessageRewriter 时，运行中被改写掉的中间消息不包含在内
+	Messages []*schema.Message
+	// Skill 本次压缩得到的 skill，没有压缩时为 nil
+	Skill *CompressedSkill
+	// CompressErr 压缩失败的错误，不影响主 react 的输出，Messages 中不会包含 add_skill
+	CompressErr error
+}
+
+// RestructuredContext 重构后的上下文
+type RestructuredContext struct {
+	Messages []*schema.Message
+	// Skills 压缩得到的顶层 skill，被隐藏的 skill 在其父 skill 的 Skills 中
+	Skills []*Skill
+}
+
+type compressRunKey struct{}
+
+// compressRun 单次压缩的信息，供 add_skill 使用
+type compressRun struct {
+	names    map[string]struct{} //已使用的 skill 名称
+	topLevel map[string]struct{} //可被隐藏的顶层压缩 skill
+}
+
+type addSkillArguments struct {
+	Name         string   `json:"name"`
+	Description  string   `json:"description"`
+	Instructions string   `json:"instructions"`
+	SubSkills    []string `json:"sub_skills"`
+}
+
+func NewCompressAgent(ctx context.Context, config *CompressAgentConfig) (*CompressAgent, error) {
+	if config.Agent == nil {
+		return nil, fmt.Errorf("agent config is required")
+	}
+	c := &CompressAgent{
+		hideSubSkills: config.HideSubSkills,
+		nameRewriter:  config.SkillNameRewriter,
+		presetSkills:  make(map[string]struct{}),
+	}
+	if c.nameRewriter == nil {
+		c.nameRewriter = DefaultSkillNameRewriter
+	}
+	_ = walkSkills(config.Agent.Skills, func(s *Skill) error {
+		c.presetSkills[s.Name] = struct{}{}
+		return nil
+	})
+
+	for _, tools := range [][]tool.BaseTool{config.Agent.ToolsConfig.Tools, config.Agent.ExtraTools} {
+		tm, err := toolsToMap(ctx, tools)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := tm[AddSkillToolName]; ok {
+			return nil, fmt.Errorf("tool %s is reserved by compress agent", AddSkillToolName)
+		}
+	}
+
+	agentConfig := *config.Agent
+	agentConfig.DynamicSkills = true
+	agent, err := NewAgent(ctx, &agentConfig)
+	if err != nil {
+		return nil, err
+	}
+	c.agent = agent
+
+	compressModel := config.CompressModel
+	if compressModel == nil {
+		compressModel = config.Agent.ToolCallingModel
+	}
+	prompt := config.CompressPrompt
+	if prompt == "" {
+		prompt = CompressSystemPrompt
+	}
+	maxStep := config.CompressMaxStep
+	if maxStep <= 0 {
+		maxStep = defaultCompressMaxStep
+	}
+	addSkill := c.addSkillTool()
+	// 压缩者没有 skill 和额外的 tool，不会绑定任何 special tool，只能看到 add_skill
+	c.compressor, err = NewAgent(ctx, &AgentConfig{
+		ToolCallingModel:   compressModel,
+		ToolsConfig:        compose.ToolsNodeConfig{Tools: []tool.BaseTool{addSkill}},
+		ToolReturnDirectly: map[string]struct{}{AddSkillToolName: {}},
+		MaxStep:            maxStep,
+		MessageModifier: func(_ context.Context, input []*schema.Message) []*schema.Message {
+			return append([]*schema.Message{schema.SystemMessage(prompt)}, input...)
+		},
+		GraphName: "CompressAgent",
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Generate 重构上下文后运行主 react，再运行压缩者。主 react 失败时直接返回错误，压缩失败记录在 CompressOutput.CompressErr 中。
+func (c *CompressAgent) Generate(ctx context.Context, input []*schema.Message, opts ...Option) (*CompressOutput, error) {
+	rc, err := c.Restructure(input)
+	if err != nil {
+		return nil, err
+	}
+	var transcript []*schema.Message
+	opts = append(opts, WithSkills(rc.Skills...), withMessages(func(msgs []*schema.Message) {
+		transcript = append(transcript[:0], msgs...)
+	}))
+	msg, err := c.agent.Generate(ctx, rc.Messages, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if len(transcript) == 0 {
+		transcript = append(transcript, rc.Messages...)
+	}
+
+	out := &CompressOutput{Message: msg, Messages: append(runMessages(rc.Messages, transcript), msg)}
+	call, result, skill, err := c.compress(ctx, rc, append(transcript, msg))
+	if err != nil {
+		out.CompressErr = err
+		return out, nil
+	}
+	if skill != nil {
+		out.Skill = skill
+		out.Messages = append(out.Messages, call, result)
+	}
+	return out, nil
+}
+
+// compress 运行压缩者，没有调用 add_skill 时返回的 skill 为 nil
+func (c *CompressAgent) compress(ctx context.Context, rc *RestructuredContext, msgs []*schema.Message) (call, result *schema.Message, skill *CompressedSkill, err error) {
+	run := &compressRun{names: make(map[string]struct{}), topLevel: make(map[string]struct{})}
+	for name := range c.presetSkills {
+		run.names[name] = struct{}{}
+	}
+	_ = walkSkills(rc.Skills, func(s *Skill) error {
+		run.names[s.Name] = struct{}{}
+		return nil
+	})
+	for _, s := range rc.Skills {
+		run.topLevel[s.Name] = struct{}{}
+	}
+
+	var compressorTranscript []*schema.Message
+	output, err := c.compressor.Generate(context.WithValue(ctx, compressRunKey{}, run),
+		[]*schema.Message{schema.UserMessage(c.compressInput(rc, msgs))},
+		withMessages(func(msgs []*schema.Message) {
+			compressorTranscript = append(compressorTranscript[:0], msgs...)
+		}),
+		withForcedTool(AddSkillToolName))
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("compress fail: %w", err)
+	}
+	if output.Role != schema.Tool || output.ToolName != AddSkillToolName {
+		// 模型没有遵循 tool choice，其输出全部丢弃
+		return nil, nil, nil, nil
+	}
+	skill = &CompressedSkill{}
+	if err = json.Unmarshal([]byte(output.Content), skill); err != nil {
+		return nil, nil, nil, fmt.Errorf("parse %s result fail: %w", AddSkillToolName, err)
+	}
+	// 只保留与结果对应的 tool call，其它输出丢弃
+	for i := len(compressorTranscript) - 1; i >= 0 && call == nil; i-- {
+		for _, tc := range compressorTranscript[i].ToolCalls {
+			if tc.ID == output.ToolCallID {
+				call = schema.AssistantMessage("", []schema.ToolCall{tc})
+				break
+			}
+		}
+	}
+	if call == nil {
+		return nil, nil, nil, fmt.Errorf("%s tool call %s is not found", AddSkillToolName, output.ToolCallID)
+	}
+	return call, output, skill, nil
+}
+
+// runMessages 主 react 本次运行中产生的 tool call 和 tool 结果，即 transcript 中不属于 input 的 assistant tool call 和 tool message
+func runMessages(input, transcript []*schema.Message) []*schema.Message {
+	inputs := make(map[*schema.Message]struct{}, len(input))
+	for _, msg := range input {
+		inputs[msg] = struct{}{}
+	}
+	var msgs []*schema.Message
+	for _, msg := range transcript {
+		if _, ok := inputs[msg]; ok {
+			continue
+		}
+		if msg.Role == schema.Tool || (msg.Role == schema.Assistant && len(msg.ToolCalls) > 0) {
+			msgs = append(msgs, msg)
+		}
+	}
+	return msgs
+}
+
+// withForcedTool 强制 ChatModel 调用 name 对应的 tool
+func withForcedTool(name string) Option {
+	return func(a *Agent) ([]agent.AgentOption, error) {
+		return []agent.AgentOption{agent.WithComposeOptions(compose.WithChatModelOption(model.WithToolChoice(schema.ToolChoiceForced, name)))}, nil
+	}
+}
+
+// compressInput 将需要压缩的对话渲染为文本交给压缩者，避免其中的 tool call 与压缩者的 tool 混淆，
+// 固定的消息在重构时原样保留，不交给压缩者
+func (c *CompressAgent) compressInput(rc *RestructuredContext, msgs []*schema.Message) string {
+	var sb strings.Builder
+	if c.hideSubSkills && len(rc.Skills) > 0 {
+		sb.WriteString(CompressSkillListPrompt)
+		for _, s := range rc.Skills {
+			sb.WriteString(fmt.Sprintf("\n- %s: %s", s.Name, s.Description))
+		}
+		sb.WriteString("\n\n")
+	}
+	sb.WriteString(CompressContextPrompt)
+	for _, g := range groupMessages(msgs) {
+		if !g.pinned {
+			renderMessages(&sb, msgs[g.start:g.end])
+		}
+	}
+	return sb.String()
+}
+
+// renderMessages 将消息渲染为文本，跳过 system message
+func renderMessages(sb *strings.Builder, msgs []*schema.Message) {
+	for _, msg := range msgs {
+		if msg.Role == schema.System {
+			continue
+		}
+		switch {
+		case msg.Role == schema.Tool:
+			sb.WriteString(fmt.Sprintf("\n[tool %s 的结果] %s", msg.ToolName, msg.Content))
+		case len(msg.ToolCalls) > 0:
+			if msg.Content != "" {
+				sb.WriteString(fmt.Sprintf("\n[%s] %s", msg.Role, msg.Content))
+			}
+			for _, tc := range msg.ToolCalls {
+				sb.WriteString(fmt.Sprintf("\n[%s 调用 tool %s] %s", msg.Role, tc.Function.Name, tc.Function.Arguments))
+			}
+		default:
+			sb.WriteString(fmt.Sprintf("\n[%s] %s", msg.Role, msg.Content))
+		}
+	}
+}
+
+func (c *CompressAgent) addSkillTool() tool.BaseTool {
+	params := map[string]*schema.ParameterInfo{
+		"name":         {Type: schema.String, Desc: "skill 的名称", Required: true},
+		"description":  {Type: schema.String, Desc: "一行简介", Required: true},
+		"instructions": {Type: schema.String, Desc: "这段对话的完整总结", Required: true},
+	}
+	if c.hideSubSkills {
+		params["sub_skills"] = &schema.ParameterInfo{
+			Type:     schema.Array,
+			ElemInfo: &schema.ParameterInfo{Type: schema.String},
+			Desc:     "可选，被这段对话引用、需要隐藏到新 skill 之下的已有 skill 名称",
+		}
+	}
+	info := &schema.ToolInfo{
+		Name:        AddSkillToolName,
+		Desc:        AddSkillToolDescription,
+		ParamsOneOf: schema.NewParamsOneOfByParams(params),
+	}
+	return utils.NewTool(info, func(ctx context.Context, input *addSkillArguments) (*CompressedSkill, error) {
+		if input.Name == "" || input.Instructions == "" {
+			return nil, fmt.Errorf("name and instructions are required")
+		}
+		run, _ := ctx.Value(compressRunKey{}).(*compressRun)
+		if run == nil {
+			run = &compressRun{}
+		}
+		name, err := rewriteSkillName(input.Name, run.names, c.nameRewriter)
+		if err != nil {
+			return nil, err
+		}
+		skill := &CompressedSkill{Name: name, Description: input.Description, Instructions: input.Instructions}
+		if c.hideSubSkills {
+			for _, sub := range input.SubSkills {
+				// 只能隐藏顶层的压缩 skill，预设的 skill 和不存在的名称被忽略
+				if _, ok := run.topLevel[sub]; ok {
+					skill.SubSkills = append(skill.SubSkills, sub)
+				}
+			}
+		}
+		return skill, nil
+	})
+}
+
+// Restructure 重构上下文：每段以 add_skill 的结果结尾的子上下文被替换为 skill，
+// 返回 system message、固定的消息、最后一次 add_skill 之后的消息和压缩得到的 skill
+func (c *CompressAgent) Restructure(input []*schema.Message) (*RestructuredContext, error) {
+	names := make(map[string]struct{}, len(c.presetSkills))
+	for name := range c.presetSkills {
+		names[name] = struct{}{}
+	}
+	toolCalls := make(map[string]string) //tool_call_id -> tool name
+	var (
+		skills []*Skill
+		end    = -1
+	)
+	for i, msg := range input {
+		for _, tc := range msg.ToolCalls {
+			toolCalls[tc.ID] = tc.Function.Name
+		}
+		if !isAddSkillResult(msg, toolCalls) {
+			continue
+		}
+		record := &CompressedSkill{}
+		if err := json.Unmarshal([]byte(msg.Content), record); err != nil {
+			return nil, fmt.Errorf("parse %s result at message %d fail: %w", AddSkillToolName, i, err)
+		}
+		name, err := rewriteSkillName(record.Name, names, c.nameRewriter)
+		if err != nil {
+			return nil, err
+		}
+		skill := &Skill{Name: name, Description: record.Description, Instructions: record.Instructions}
+		if c.hideSubSkills {
+			for _, sub := range record.SubSkills {
+				for j, top := range skills {
+					if top.Name == sub {
+						skill.Skills = append(skill.Skills, top)
+						skills = append(skills[:j], skills[j+1:]...)
+						break
+					}
+				}
+			}
+		}
+		skills = append(skills, skill)
+		end = i
+	}
+
+	rc := &RestructuredContext{Skills: skills, Messages: make([]*schema.Message, 0, len(input)-end)}
+	for _, g := range groupMessages(input[:end+1]) {
+		if g.pinned {
+			rc.Messages = append(rc.Messages, input[g.start:g.end]...)
+		}
+	}
+	rc.Messages = append(rc.Messages, input[end+1:]...)
+	return rc, nil
+}
+
+func isAddSkillResult(msg *schema.Message, toolCalls map[string]string) bool {
+	if msg.Role != schema.Tool {
+		return false
+	}
+	if msg.ToolName != "" {
+		return msg.ToolName == AddSkillToolName
+	}
+	return toolCalls[msg.ToolCallID] == AddSkillToolName
+}
+
+// rewriteSkillName 名称重复时使用 rewriter 重写，并登记到 names 中
+func rewriteSkillName(name string, names map[string]struct{}, rewriter SkillNameRewriter) (string, error) {
+	if names == nil {
+		return name, nil
+	}
+	exists := func(n string) bool {
+		_, ok := names[n]
+		return ok
+	}
+	if exists(name) {
+		rewritten := rewriter(name, exists)
+		if rewritten == "" || exists(rewritten) {
+			return "", fmt.Errorf("skill name %s is duplicated, rewritten name %q is invalid", name, rewritten)
+		}
+		name = rewritten
+	}
+	names[name] = struct{}{}
+	return name, nil
+}
+
+// DefaultSkillNameRewriter 在名称后追加 _2、_3 ...
+func DefaultSkillNameRewriter(name string, exists func(name string) bool) string {
+	for i := 2; ; i++ {
+		if n := fmt.Sprintf("%s_%d", name, i); !exists(n) {
+			return n
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package t_eino
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+)
+
+func addSkillCall(id, name string, subSkills ...string) *schema.Message {
+	args := fmt.Sprintf(`{"name":%q,"description":"d","instructions":"summary of %s"}`, name, name)
+	if len(subSkills) > 0 {
+		args = fmt.Sprintf(`{"name":%q,"description":"d","instructions":"summary of %s","sub_skills":["%s"]}`, name, name, strings.Join(subSkills, `","`))
+	}
+	return toolCallMessage(newToolCall(id, AddSkillToolName, args))
+}
+
+func addSkillResult(id, name string, subSkills ...string) *schema.Message {
+	content := fmt.Sprintf(`{"name":%q,"description":"d","instructions":"summary of %s"}`, name, name)
+	if len(subSkills) > 0 {
+		content = fmt.Sprintf(`{"name":%q,"description":"d","instructions":"summary of %s","sub_skills":["%s"]}`, name, name, strings.Join(subSkills, `","`))
+	}
+	return schema.ToolMessage(content, id, schema.WithToolName(AddSkillToolName))
+}
+
+// roles 按顺序列出消息的角色，assistant 的 tool call 记为 assistant(<tool name>)
+func roles(msgs []*schema.Message) string {
+	var parts []string
+	for _, msg := range msgs {
+		role := string(msg.Role)
+		for _, tc := range msg.ToolCalls {
+			role += "(" + tc.Function.Name + ")"
+		}
+		parts = append(parts, role)
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestCompressAgentGenerate(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		// main 主 react 的回复
+		main         func(in []*schema.Message) *schema.Message
+		wantMessages string
+	}{
+		{
+			name:         "direct answer",
+			main:         func(in []*schema.Message) *schema.Message { return schema.AssistantMessage("answer", nil) },
+			wantMessages: "assistant assistant(add_skill) tool",
+		},
+		{
+			// 主 react 的 tool call 和结果需要追加到上下文中
+			name: "tool calls kept",
+			main: func(in []*schema.Message) *schema.Message {
+				if in[len(in)-1].Role == schema.User {
+					return toolCallMessage(newToolCall("c1", "echo", `{"q":"x"}`))
+				}
+				return schema.AssistantMessage("answer", nil)
+			},
+			wantMessages: "assistant(echo) tool assistant assistant(add_skill) tool",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				compressorTools [][]string
+				toolChoices     []*model.Options
+			)
+			compressor := &scriptedModel{
+				reply: func(in []*schema.Message, tools []*schema.ToolInfo) *schema.Message {
+					compressorTools = append(compressorTools, toolNames(tools))
+					return addSkillCall("a1", "topic")
+				},
+				onCall: func(o *model.Options) { toolChoices = append(toolChoices, o) },
+			}
+			ca, err := NewCompressAgent(ctx, &CompressAgentConfig{
+				Agent: &AgentConfig{
+					ToolCallingModel: &scriptedModel{reply: func(in []*schema.Message, tools []*schema.ToolInfo) *schema.Message { return tt.main(in) }},
+					ToolsConfig:      compose.ToolsNodeConfig{Tools: []tool.BaseTool{newTestTool("echo", "d")}},
+				},
+				CompressModel: compressor,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			out, err := ca.Generate(ctx, []*schema.Message{schema.UserMessage("hi")})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out.CompressErr != nil {
+				t.Fatal(out.CompressErr)
+			}
+			if out.Skill == nil || out.Skill.Name != "topic" {
+				t.Fatalf("got skill %+v, want topic", out.Skill)
+			}
+			if got := roles(out.Messages); got != tt.wantMessages {
+				t.Fatalf("got messages %q, want %q", got, tt.wantMessages)
+			}
+			if fmt.Sprint(compressorTools) != "[[add_skill]]" {
+				t.Fatalf("compressor tools %v, want only add_skill", compressorTools)
+			}
+			o := toolChoices[0]
+			if o.ToolChoice == nil || *o.ToolChoice != schema.ToolChoiceForced || fmt.Sprint(o.AllowedToolNames) != "[add_skill]" {
+				t.Fatalf("compressor tool choice %v %v, want forced add_skill", o.ToolChoice, o.AllowedToolNames)
+			}
+		})
+	}
+}
+
+func TestCompressAgentRestructure(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name          string
+		hideSubSkills bool
+		preset        []*Skill
+		input         []*schema.Message
+		wantSkills    string
+		// wantMessages 重构后消息的内容
+		wantMessages string
+	}{
+		{
+			name:         "no compressed context",
+			input:        []*schema.Message{schema.SystemMessage("sys"), schema.UserMessage("q1")},
+			wantMessages: "sys q1",
+		},
+		{
+			name: "replace sub context",
+			input: []*schema.Message{
+				schema.SystemMessage("sys"), schema.UserMessage("q1"), schema.AssistantMessage("a1", nil),
+				addSkillCall("s1", "topic"), addSkillResult("s1", "topic"),
+				schema.UserMessage("q2"),
+			},
+			wantSkills:   "topic",
+			wantMessages: "sys q2",
+		},
+		{
+			name: "pinned message kept",
+			input: []*schema.Message{
+				schema.UserMessage("q1"), PinMessage(schema.UserMessage("pinned")), schema.AssistantMessage("a1", nil),
+				addSkillCall("s1", "topic"), addSkillResult("s1", "topic"),
+			},
+			wantSkills:   "topic",
+			wantMessages: "pinned",
+		},
+		{
+			name:   "duplicated names rewritten",
+			preset: []*Skill{{Name: "topic", Description: "preset"}},
+			input: []*schema.Message{
+				schema.UserMessage("q1"), addSkillCall("s1", "topic"), addSkillResult("s1", "topic"),
+				schema.UserMessage("q2"), addSkillCall("s2", "topic"), addSkillResult("s2", "topic"),
+			},
+			wantSkills: "topic_2 topic_3",
+		},
+		{
+			name:          "nested sub skills",
+			hideSubSkills: true,
+			input: []*schema.Message{
+				schema.UserMessage("q1"), addSkillCall("s1", "a"), addSkillResult("s1", "a"),
+				schema.UserMessage("q2"), addSkillCall("s2", "b", "a"), addSkillResult("s2", "b", "a"),
+				schema.UserMessage("q3"),
+			},
+			wantSkills:   "b[a]",
+			wantMessages: "q3",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ca, err := NewCompressAgent(ctx, &CompressAgentConfig{
+				Agent: &AgentConfig{
+					ToolCallingModel: &scriptedModel{reply: func([]*schema.Message, []*schema.ToolInfo) *schema.Message { return schema.AssistantMessage("", nil) }},
+					Skills:           tt.preset,
+				},
+				HideSubSkills: tt.hideSubSkills,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			rc, err := ca.Restructure(tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var skills []string
+			for _, s := range rc.Skills {
+				name := s.Name
+				if len(s.Skills) > 0 {
+					var children []string
+					for _, child := range s.Skills {
+						children = append(children, child.Name)
+					}
+					name += "[" + strings.Join(children, " ") + "]"
+				}
+				skills = append(skills, name)
+			}
+			if got := strings.Join(skills, " "); got != tt.wantSkills {
+				t.Fatalf("got skills %q, want %q", got, tt.wantSkills)
+			}
+			var contents []string
+			for _, msg := range rc.Messages {
+				contents = append(contents, msg.Content)
+			}
+			if got := strings.Join(contents, " "); got != tt.wantMessages {
+				t.Fatalf("got messages %q, want %q", got, tt.wantMessages)
+			}
+		})
+	}
+}
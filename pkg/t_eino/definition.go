@@ -0,0 +1,330 @@
+package t_eino
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+	"gopkg.in/yaml.v3"
+)
+
+// AgentDefinition 声明式的 agent 定义，使用 yaml 或 json 编写，tool 和 rewriter 通过 Registry 按名称引用
+type AgentDefinition struct {
+	Name  string          `yaml:"name" json:"name"`
+	Model ModelDefinition `yaml:"model" json:"model"`
+	// FallbackModels 主模型遇到超时、429、5xx 或空响应时按顺序使用的备用模型
+	FallbackModels []ModelDefinition `yaml:"fallback_models" json:"fallback_models"`
+	// RouteModels 只能被 Routes 选中的模型，Routes 中按 name 引用主模型、备用模型或路由模型
+	RouteModels []ModelDefinition `yaml:"route_models" json:"route_models"`
+	Routes      []*RouteRule      `yaml:"routes" json:"routes"`
+
+	// SystemPrompt text/template 模板，每次调用 ChatModel 前渲染，可使用 {{.Vars.xxx}} 和 {{.Now}}
+	SystemPrompt string            `yaml:"system_prompt" json:"system_prompt"`
+	PromptVars   map[string]string `yaml:"prompt_vars" json:"prompt_vars"`
+
+	Tools          []ToolSpec        `yaml:"tools" json:"tools"`                     //config tools
+	ExtraTools     []ToolSpec        `yaml:"extra_tools" json:"extra_tools"`         //每次运行默认注入的额外 tool
+	ReturnDirectly []string          `yaml:"return_directly" json:"return_directly"` //tool 的名称
+	Skills         *SkillsDefinition `yaml:"skills" json:"skills"`
+
+	MaxStep       int `yaml:"max_step" json:"max_step"`
+	MaxAliveTools int `yaml:"max_alive_tools" json:"max_alive_tools"`
+	MaxToolTokens int `yaml:"max_tool_tokens" json:"max_tool_tokens"`
+
+	Rewriter *RewriterDefinition `yaml:"rewriter" json:"rewriter"`
+	FollowUp *FollowUpDefinition `yaml:"follow_up" json:"follow_up"`
+
+	GraphName     string `yaml:"graph_name" json:"graph_name"`
+	ModelNodeName string `yaml:"model_node_name" json:"model_node_name"`
+	ToolsNodeName string `yaml:"tools_node_name" json:"tools_node_name"`
+}
+
+// ModelDefinition 字符串字段中的 ${ENV} 会被替换为环境变量
+type ModelDefinition struct {
+	Name        string            `yaml:"name" json:"name"`         //记录在输出 Extra 中的名称，主模型默认 primary，备用模型和路由模型默认为 model
+	Provider    string            `yaml:"provider" json:"provider"` //ark、openai、ollama 或 Registry 中注册的 provider
+	Model       string            `yaml:"model" json:"model"`
+	APIKey      string            `yaml:"api_key" json:"api_key"`
+	BaseURL     string            `yaml:"base_url" json:"base_url"`
+	Timeout     string            `yaml:"timeout" json:"timeout"` //如 60s
+	Headers     map[string]string `yaml:"headers" json:"headers"`
+	Temperature *float32          `yaml:"temperature" json:"temperature"`
+	TopP        *float32          `yaml:"top_p" json:"top_p"`
+	MaxTokens   *int              `yaml:"max_tokens" json:"max_tokens"`
+}
+
+// SkillsDefinition 从 Dir 加载 skill，Dir 为相对路径时相对于定义文件所在目录
+type SkillsDefinition struct {
+	Dir   string     `yaml:"dir" json:"dir"`
+	Tools []ToolSpec `yaml:"tools" json:"tools"` //skill 引用的 tool
+}
+
+// FollowUpDefinition 下一步推荐，Model 为空时使用主模型
+type FollowUpDefinition struct {
+	Model       *ModelDefinition `yaml:"model" json:"model"`
+	Count       int              `yaml:"count" json:"count"`
+	Prompt      string           `yaml:"prompt" json:"prompt"` //%d 为问题数量
+	MaxMessages int              `yaml:"max_messages" json:"max_messages"`
+}
+
+type RewriterDefinition struct {
+	Name   string         `yaml:"name" json:"name"`
+	Config map[string]any `yaml:"config" json:"config"`
+}
+
+// promptData 渲染 SystemPrompt 的数据
+type promptData struct {
+	Vars map[string]string
+	Now  time.Time
+}
+
+// LoadAgent 读取定义文件，校验后创建 Agent，registry 为 nil 时使用 DefaultRegistry
+func LoadAgent(ctx context.Context, path string, registry *Registry) (*Agent, error) {
+	def, err := ReadAgentDefinition(path)
+	if err != nil {
+		return nil, err
+	}
+	config, err := def.AgentConfig(ctx, registry)
+	if err != nil {
+		return nil, fmt.Errorf("agent %s: %w", def.Name, err)
+	}
+	return NewAgent(ctx, config)
+}
+
+// ReadAgentDefinition 读取并校验定义文件，json 是 yaml 的子集，两者都可以解析
+func ReadAgentDefinition(path string) (*AgentDefinition, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	def := &AgentDefinition{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err = decoder.Decode(def); err != nil {
+		return nil, fmt.Errorf("parse agent definition %s fail: %w", path, err)
+	}
+	if def.Skills != nil && def.Skills.Dir != "" && !filepath.IsAbs(def.Skills.Dir) {
+		def.Skills.Dir = filepath.Join(filepath.Dir(path), def.Skills.Dir)
+	}
+	if err = def.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid agent definition %s: %w", path, err)
+	}
+	return def, nil
+}
+
+// Validate 校验不依赖 Registry 的部分
+func (d *AgentDefinition) Validate() error {
+	if err := d.Model.validate("model"); err != nil {
+		return err
+	}
+	for i := range d.FallbackModels {
+		if err := d.FallbackModels[i].validate(fmt.Sprintf("fallback_models[%d]", i)); err != nil {
+			return err
+		}
+	}
+	for i := range d.RouteModels {
+		if err := d.RouteModels[i].validate(fmt.Sprintf("route_models[%d]", i)); err != nil {
+			return err
+		}
+	}
+	for i, rule := range d.Routes {
+		if rule == nil || rule.Model == "" {
+			return fmt.Errorf("routes[%d].model is required", i)
+		}
+	}
+	if d.MaxStep < 0 || d.MaxAliveTools < 0 || d.MaxToolTokens < 0 {
+		return fmt.Errorf("max_step, max_alive_tools and max_tool_tokens must not be negative")
+	}
+	if _, err := template.New("system_prompt").Parse(d.SystemPrompt); err != nil {
+		return fmt.Errorf("invalid system_prompt: %w", err)
+	}
+	for _, spec := range append(append([]ToolSpec(nil), d.Tools...), d.ExtraTools...) {
+		if spec.Name == "" {
+			return fmt.Errorf("tool name is required")
+		}
+	}
+	if d.Skills != nil && d.Skills.Dir == "" {
+		return fmt.Errorf("skills.dir is required")
+	}
+	if d.Rewriter != nil && d.Rewriter.Name == "" {
+		return fmt.Errorf("rewriter.name is required")
+	}
+	if d.FollowUp != nil {
+		if d.FollowUp.Count < 0 || d.FollowUp.MaxMessages < 0 {
+			return fmt.Errorf("follow_up.count and follow_up.max_messages must not be negative")
+		}
+		if d.FollowUp.Model != nil {
+			if err := d.FollowUp.Model.validate("follow_up.model"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// AgentConfig 按定义创建 AgentConfig，调用方可在 NewAgent 前继续修改。
+// tool 之间以及与 special tools 的重名在 NewAgent 时检查。
+func (d *AgentDefinition) AgentConfig(ctx context.Context, registry *Registry) (*AgentConfig, error) {
+	if err := d.Validate(); err != nil {
+		return nil, err
+	}
+	if registry == nil {
+		registry = DefaultRegistry
+	}
+	chatModel, err := registry.ChatModel(ctx, d.Model.config())
+	if err != nil {
+		return nil, err
+	}
+	tools, err := registry.Tools(ctx, d.Tools...)
+	if err != nil {
+		return nil, err
+	}
+	extraTools, err := registry.Tools(ctx, d.ExtraTools...)
+	if err != nil {
+		return nil, err
+	}
+	toolNames := make(map[string]struct{}, len(tools)+len(extraTools))
+	for _, tl := range append(append([]tool.BaseTool(nil), tools...), extraTools...) {
+		info, err := tl.Info(ctx)
+		if err != nil {
+			return nil, err
+		}
+		toolNames[info.Name] = struct{}{}
+	}
+	for _, name := range d.ReturnDirectly {
+		if _, ok := toolNames[name]; !ok {
+			return nil, fmt.Errorf("return_directly tool %s is not in tools or extra_tools", name)
+		}
+	}
+
+	fallbacks, err := namedModels(ctx, registry, d.FallbackModels)
+	if err != nil {
+		return nil, err
+	}
+	routeModels, err := namedModels(ctx, registry, d.RouteModels)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &AgentConfig{
+		ToolCallingModel: chatModel,
+		ModelName:        d.Model.Name,
+		FallbackModels:   fallbacks,
+		RouteModels:      routeModels,
+		RouteRules:       d.Routes,
+		ToolsConfig:      compose.ToolsNodeConfig{Tools: tools},
+		ExtraTools:       extraTools,
+		MaxAliveTools:    d.MaxAliveTools,
+		MaxToolTokens:    d.MaxToolTokens,
+		MaxStep:          d.MaxStep,
+		GraphName:        d.GraphName,
+		ModelNodeName:    d.ModelNodeName,
+		ToolsNodeName:    d.ToolsNodeName,
+	}
+	if len(d.ReturnDirectly) > 0 {
+		config.ToolReturnDirectly = make(map[string]struct{}, len(d.ReturnDirectly))
+		for _, name := range d.ReturnDirectly {
+			config.ToolReturnDirectly[name] = struct{}{}
+		}
+	}
+	if d.Skills != nil {
+		skillTools, err := registry.Tools(ctx, d.Skills.Tools...)
+		if err != nil {
+			return nil, err
+		}
+		if config.Skills, err = LoadSkills(ctx, d.Skills.Dir, skillTools...); err != nil {
+			return nil, err
+		}
+	}
+	if d.Rewriter != nil {
+		if config.MessageRewriter, err = registry.Rewriter(ctx, d.Rewriter.Name, d.Rewriter.Config); err != nil {
+			return nil, err
+		}
+	}
+	if d.FollowUp != nil {
+		config.FollowUp = &FollowUpConfig{Count: d.FollowUp.Count, Prompt: d.FollowUp.Prompt, MaxMessages: d.FollowUp.MaxMessages}
+		if d.FollowUp.Model != nil {
+			if config.FollowUp.Model, err = registry.ChatModel(ctx, d.FollowUp.Model.config()); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if strings.TrimSpace(d.SystemPrompt) != "" {
+		tmpl := template.Must(template.New("system_prompt").Parse(d.SystemPrompt))
+		vars := d.PromptVars
+		config.MessageModifier = func(_ context.Context, input []*schema.Message) []*schema.Message {
+			var sb strings.Builder
+			if err := tmpl.Execute(&sb, &promptData{Vars: vars, Now: time.Now()}); err != nil {
+				// 模板已校验，执行失败时退化为原始模板
+				sb.Reset()
+				sb.WriteString(d.SystemPrompt)
+			}
+			return append([]*schema.Message{schema.SystemMessage(sb.String())}, input...)
+		}
+	}
+	return config, nil
+}
+
+// namedModels 创建备用模型和路由模型，name 默认为 model
+func namedModels(ctx context.Context, registry *Registry, defs []ModelDefinition) ([]*NamedModel, error) {
+	models := make([]*NamedModel, 0, len(defs))
+	for i := range defs {
+		m := &defs[i]
+		llm, err := registry.ChatModel(ctx, m.config())
+		if err != nil {
+			return nil, err
+		}
+		name := m.Name
+		if name == "" {
+			name = os.ExpandEnv(m.Model)
+		}
+		models = append(models, &NamedModel{Name: name, Model: llm})
+	}
+	return models, nil
+}
+
+func (d *ModelDefinition) validate(field string) error {
+	if d.Provider == "" {
+		return fmt.Errorf("%s.provider is required", field)
+	}
+	if d.Model == "" {
+		return fmt.Errorf("%s.model is required", field)
+	}
+	if d.Timeout != "" {
+		if _, err := time.ParseDuration(d.Timeout); err != nil {
+			return fmt.Errorf("invalid %s.timeout: %w", field, err)
+		}
+	}
+	return nil
+}
+
+// config 转换为 ModelConfig，替换字符串中的 ${ENV}
+func (d *ModelDefinition) config() *ModelConfig {
+	c := &ModelConfig{
+		Provider:    d.Provider,
+		Model:       os.ExpandEnv(d.Model),
+		APIKey:      os.ExpandEnv(d.APIKey),
+		BaseURL:     os.ExpandEnv(d.BaseURL),
+		Temperature: d.Temperature,
+		TopP:        d.TopP,
+		MaxTokens:   d.MaxTokens,
+	}
+	if d.Timeout != "" {
+		c.Timeout, _ = time.ParseDuration(d.Timeout)
+	}
+	if len(d.Headers) > 0 {
+		c.Headers = make(map[string]string, len(d.Headers))
+		for k, v := range d.Headers {
+			c.Headers[k] = os.ExpandEnv(v)
+		}
+	}
+	return c
+}
@@ -0,0 +1,136 @@
+package t_eino
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+func TestReadAgentDefinitionExample(t *testing.T) {
+	path := filepath.Join("..", "..", "docs", "agent.example.yaml")
+	def, err := ReadAgentDefinition(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		got  any
+		want any
+	}{
+		{name: "name", got: def.Name, want: "assistant"},
+		{name: "model", got: def.Model.Provider + " " + def.Model.Timeout, want: "ark 60s"},
+		{name: "fallback model", got: def.FallbackModels[0].Name, want: "backup"},
+		{name: "route", got: def.Routes[0].Name + " " + def.Routes[0].Model, want: "dispatch cheap"},
+		{name: "route condition", got: *def.Routes[0].AfterToolResult, want: true},
+		{name: "tool config", got: def.Tools[1].Name + " " + def.Tools[1].Config["timeout"].(string), want: "http_get 10s"},
+		{name: "extra tools", got: len(def.ExtraTools), want: 2},
+		// 相对路径相对于定义文件所在目录
+		{name: "skills dir", got: def.Skills.Dir, want: filepath.Join(filepath.Dir(path), "skills")},
+		{name: "rewriter", got: def.Rewriter.Name, want: RewriterSlidingWindow},
+		{name: "follow up", got: def.FollowUp.Count, want: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Fatalf("got %v, want %v", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadAgentDefinitionInvalid(t *testing.T) {
+	const model = "model: {provider: openai, model: m}\n"
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{name: "unknown field", yaml: model + "max_steps: 3\n", wantErr: "field max_steps not found"},
+		{name: "no provider", yaml: "model: {model: m}\n", wantErr: "model.provider is required"},
+		{name: "invalid timeout", yaml: "model: {provider: openai, model: m, timeout: soon}\n", wantErr: "invalid model.timeout"},
+		{name: "invalid fallback model", yaml: model + "fallback_models: [{provider: openai}]\n", wantErr: "fallback_models[0].model is required"},
+		{name: "route without model", yaml: model + "routes: [{name: r}]\n", wantErr: "routes[0].model is required"},
+		{name: "negative max step", yaml: model + "max_step: -1\n", wantErr: "must not be negative"},
+		{name: "invalid system prompt", yaml: model + "system_prompt: '{{.Vars'\n", wantErr: "invalid system_prompt"},
+		{name: "tool without name", yaml: model + "tools: [{config: {a: 1}}]\n", wantErr: "tool name is required"},
+		{name: "skills without dir", yaml: model + "skills: {tools: [a]}\n", wantErr: "skills.dir is required"},
+		{name: "rewriter without name", yaml: model + "rewriter: {config: {max_tokens: 10}}\n", wantErr: "rewriter.name is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "agent.yaml")
+			if err := os.WriteFile(path, []byte(tt.yaml), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := ReadAgentDefinition(path)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got %v, want error %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadAgent(t *testing.T) {
+	ctx := context.Background()
+	registry := DefaultRegistry.Scope()
+	// fake 回答 system prompt 和可用的 tool
+	err := registry.RegisterModelProvider("fake", func(ctx context.Context, config *ModelConfig) (model.ToolCallingChatModel, error) {
+		return &scriptedModel{reply: func(in []*schema.Message, tools []*schema.ToolInfo) *schema.Message {
+			return schema.AssistantMessage(in[0].Content+" "+strings.Join(toolNames(tools), ","), nil)
+		}}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = registry.RegisterTools(ctx, newTestTool("echo", "d")); err != nil {
+		t.Fatal(err)
+	}
+	const model = "model: {provider: fake, model: m}\n"
+	tests := []struct {
+		name    string
+		yaml    string
+		want    string
+		wantErr string
+	}{
+		{
+			name: "system prompt and tools",
+			yaml: model + "system_prompt: 'hi {{.Vars.who}}'\nprompt_vars: {who: bob}\ntools: [echo]\nreturn_directly: [echo]\n",
+			want: "hi bob echo",
+		},
+		{name: "unknown provider", yaml: "model: {provider: nope, model: m}\n", wantErr: "unsupported model provider nope"},
+		{name: "unknown tool", yaml: model + "tools: [nope]\n", wantErr: "nope"},
+		{name: "unknown return directly tool", yaml: model + "tools: [echo]\nreturn_directly: [nope]\n", wantErr: "return_directly tool nope"},
+		{name: "unknown rewriter", yaml: model + "rewriter: {name: nope}\n", wantErr: "nope"},
+		{name: "unknown route model", yaml: model + "routes: [{model: nope}]\n", wantErr: "model nope is not configured"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "agent.yaml")
+			if err := os.WriteFile(path, []byte(tt.yaml), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			a, err := LoadAgent(ctx, path, registry)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got %v, want error %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			msg, err := a.Generate(ctx, []*schema.Message{schema.UserMessage("hi")})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if msg.Content != tt.want {
+				t.Fatalf("got %q, want %q", msg.Content, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package t_eino
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/cloudwego/eino/compose"
+)
+
+// 错误类型，使用 errors.Is 判断，如 errors.Is(err, ErrToolNotFound)
+var (
+	ErrMaxStepsExceeded = errors.New("max steps exceeded")
+	ErrToolNotFound     = errors.New("tool not found")
+	ErrToolExecution    = errors.New("tool execution failed")
+	ErrModel            = errors.New("model failed")
+	ErrCanceled         = errors.New("run canceled")           //用户取消或超时，原因可用 errors.Is(err, context.DeadlineExceeded) 区分
+	ErrPinnedRemoved    = errors.New("pinned message removed") //MessageRewriter 丢弃或修改了固定的消息
+)
+
+// AgentError 运行失败的错误，Kind 为上面的错误类型之一或 StopRunErr
+type AgentError struct {
+	Kind       error
+	Step       int    //失败时 ChatModel 已被调用的次数
+	ToolCallID string //tool 相关的错误才有
+	ToolName   string
+	Err        error
+}
+
+func (e *AgentError) Error() string {
+	msg := fmt.Sprintf("%v at step %d", e.Kind, e.Step)
+	if e.ToolCallID != "" || e.ToolName != "" {
+		msg += fmt.Sprintf(" (tool %s, call %s)", e.ToolName, e.ToolCallID)
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *AgentError) Unwrap() []error {
+	if e.Err == nil {
+		return []error{e.Kind}
+	}
+	return []error{e.Kind, e.Err}
+}
+
+// NormalStop 运行正常结束：流读取完毕或被 StopRunErr 主动终止
+func NormalStop(err error) bool {
+	return errors.Is(err, io.EOF) || errors.Is(err, StopRunErr)
+}
+
+func IsMaxStepsExceeded(err error) bool {
+	return errors.Is(err, ErrMaxStepsExceeded)
+}
+
+func IsToolNotFound(err error) bool {
+	return errors.Is(err, ErrToolNotFound)
+}
+
+func IsToolExecutionFailed(err error) bool {
+	return errors.Is(err, ErrToolExecution)
+}
+
+func IsModelFailed(err error) bool {
+	return errors.Is(err, ErrModel)
+}
+
+func IsCanceled(err error) bool {
+	return errors.Is(err, ErrCanceled)
+}
+
+func IsPinnedRemoved(err error) bool {
+	return errors.Is(err, ErrPinnedRemoved)
+}
+
+func IsStopped(err error) bool {
+	return errors.Is(err, StopRunErr)
+}
+
+// errorKind 主动终止和取消优先于 fallback
+func errorKind(err error, fallback error) error {
+	switch {
+	case errors.Is(err, StopRunErr):
+		return StopRunErr
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return ErrCanceled
+	}
+	return fallback
+}
+
+// currentStep 当前运行中 ChatModel 已被调用的次数，只能在图内调用
+func currentStep(ctx context.Context) int {
+	var step int
+	_ = compose.ProcessState[*state](ctx, func(_ context.Context, s *state) error {
+		step = s.Step
+		return nil
+	})
+	return step
+}
+
+// newModelError ChatModel 失败的错误，需在图内调用
+func newModelError(ctx context.Context, err error) error {
+	var ae *AgentError
+	if err == nil || errors.As(err, &ae) {
+		return err
+	}
+	return &AgentError{Kind: errorKind(err, ErrModel), Step: currentStep(ctx), Err: err}
+}
+
+// newToolError tool 执行失败的错误，需在 tool 内调用，中断不会被包装
+func newToolError(ctx context.Context, name string, err error) error {
+	var ae *AgentError
+	if err == nil || errors.As(err, &ae) {
+		return err
+	}
+	if _, ok := compose.IsInterruptRerunError(err); ok {
+		return err
+	}
+	return &AgentError{Kind: errorKind(err, ErrToolExecution), Step: currentStep(ctx), ToolCallID: compose.GetToolCallID(ctx), ToolName: name, Err: err}
+}
+
+// errorMiddleware 将 ToolsNode 中 tool 的错误包装为 AgentError
+func errorMiddleware() compose.ToolMiddleware {
+	return compose.ToolMiddleware{
+		Invokable: func(next compose.InvokableToolEndpoint) compose.InvokableToolEndpoint {
+			return func(ctx context.Context, input *compose.ToolInput) (*compose.ToolOutput, error) {
+				output, err := next(ctx, input)
+				if err != nil {
+					return nil, newToolError(ctx, input.Name, err)
+				}
+				return output, nil
+			}
+		},
+		Streamable: func(next compose.StreamableToolEndpoint) compose.StreamableToolEndpoint {
+			return func(ctx context.Context, input *compose.ToolInput) (*compose.StreamToolOutput, error) {
+				output, err := next(ctx, input)
+				if err != nil {
+					return nil, newToolError(ctx, input.Name, err)
+				}
+				return output, nil
+			}
+		},
+	}
+}
+
+// runError 将运行结束后仍未分类的错误转换为 AgentError，step 为运行结束时 ChatModel 已被调用的次数
+func runError(step int, err error) error {
+	var ae *AgentError
+	if err == nil || errors.As(err, &ae) {
+		return err
+	}
+	if _, ok := compose.ExtractInterruptInfo(err); ok {
+		return err
+	}
+	kind := errorKind(err, nil)
+	if errors.Is(err, compose.ErrExceedMaxSteps) {
+		kind = ErrMaxStepsExceeded
+	}
+	if kind == nil {
+		return err
+	}
+	return &AgentError{Kind: kind, Step: step, Err: err}
+}
@@ -0,0 +1,99 @@
+package t_eino
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+)
+
+func TestAgentErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		// call 第 n 次调用 ChatModel 时返回的 tool call
+		call     func(n int, cancel context.CancelFunc) schema.ToolCall
+		is       func(err error) bool
+		wantStep int
+		wantTool string
+	}{
+		{
+			name:     "tool execution failed",
+			call:     func(n int, _ context.CancelFunc) schema.ToolCall { return newToolCall("x1", "boom", `{}`) },
+			is:       IsToolExecutionFailed,
+			wantStep: 1,
+			wantTool: "boom",
+		},
+		{
+			name:     "tool not found",
+			call:     func(n int, _ context.CancelFunc) schema.ToolCall { return newToolCall("x2", "nope", `{}`) },
+			is:       IsToolNotFound,
+			wantStep: 1,
+			wantTool: "nope",
+		},
+		{
+			name: "max steps exceeded",
+			call: func(n int, _ context.CancelFunc) schema.ToolCall {
+				return newToolCall(fmt.Sprint("s", n), "safe", `{}`)
+			},
+			is: IsMaxStepsExceeded,
+		},
+		{
+			name:     "stopped by tool",
+			call:     func(n int, _ context.CancelFunc) schema.ToolCall { return newToolCall("x3", "boom", `{"q":"stop"}`) },
+			is:       func(err error) bool { return IsStopped(err) && NormalStop(err) && !IsToolExecutionFailed(err) },
+			wantStep: 1,
+			wantTool: "boom",
+		},
+		{
+			name: "canceled",
+			call: func(n int, cancel context.CancelFunc) schema.ToolCall {
+				cancel()
+				return newToolCall("x4", "safe", `{}`)
+			},
+			is: IsCanceled,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			n := 0
+			llm := &scriptedModel{reply: func(in []*schema.Message, tools []*schema.ToolInfo) *schema.Message {
+				n++
+				return toolCallMessage(tt.call(n, cancel))
+			}}
+			boom := newFuncTool("boom", func(ctx context.Context, q string) (string, error) {
+				if q == "stop" {
+					return "", StopRunErr
+				}
+				return "", errors.New("bad")
+			})
+			a, err := NewAgent(ctx, &AgentConfig{
+				ToolCallingModel: llm,
+				MaxStep:          4,
+				ToolsConfig:      compose.ToolsNodeConfig{Tools: []tool.BaseTool{boom, newTestTool("safe", "s")}},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = a.Generate(ctx, []*schema.Message{schema.UserMessage("hi")})
+			if !tt.is(err) {
+				t.Fatalf("got %v, want error of kind %s", err, tt.name)
+			}
+			var ae *AgentError
+			if !errors.As(err, &ae) {
+				t.Fatalf("got %T, want AgentError", err)
+			}
+			if tt.wantStep > 0 && ae.Step != tt.wantStep {
+				t.Fatalf("got step %d, want %d", ae.Step, tt.wantStep)
+			}
+			if ae.ToolName != tt.wantTool {
+				t.Fatalf("got tool %q, want %q", ae.ToolName, tt.wantTool)
+			}
+		})
+	}
+}
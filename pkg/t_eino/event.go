@@ -0,0 +1,215 @@
+package t_eino
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"github.com/cloudwego/eino/callbacks"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+	ub "github.com/cloudwego/eino/utils/callbacks"
+)
+
+type AgentEventType string
+
+const (
+	EventRunStarted        AgentEventType = "run_started"
+	EventStepStarted       AgentEventType = "step_started"         //一次 ChatModel 调用开始
+	EventTextDelta         AgentEventType = "text_delta"           //Delta 为正文增量
+	EventReasoningDelta    AgentEventType = "reasoning_delta"      //Delta 为思考过程增量
+	EventToolCallStarted   AgentEventType = "tool_call_started"    //ChatModel 开始输出一个 tool call
+	EventToolCallArgsDelta AgentEventType = "tool_call_args_delta" //Delta 为 tool call 参数增量
+	EventToolCallFinished  AgentEventType = "tool_call_finished"   //Arguments 为完整参数
+	EventToolResult        AgentEventType = "tool_result"          //tool 执行完毕，Result 为结果
+	EventStepFinished      AgentEventType = "step_finished"        //一次 ChatModel 调用结束，Message 为完整输出
+	EventFollowUpQuestions AgentEventType = "follow_up_questions"  //配置了 FollowUp 时在 run_finished 之后输出，Questions 为推荐的问题
+	EventRunFinished       AgentEventType = "run_finished"         //Message 为最终结果
+	EventRunError          AgentEventType = "run_error"            //Err 为运行错误
+)
+
+// AgentEvent 运行过程中的事件，按 Type 读取对应的字段
+type AgentEvent struct {
+	Type  AgentEventType
+	RunID string
+	Step  int //所在的 ChatModel 调用次数，从 1 开始
+
+	Delta      string
+	ToolCallID string
+	ToolName   string
+	Arguments  string
+	Result     string
+	Message    *schema.Message
+	Session    *ToolSessionState //step_finished 时动态获取的 tool 和已激活的 skill
+	Route      *RouteDecision    //step_finished 时本次调用的路由结果，未配置路由规则时为空
+	Questions  []string
+	Err        error
+}
+
+// EventIterator 依次输出运行过程中的 AgentEvent，最后一个事件为 run_finished 或 run_error，
+// 配置了 FollowUp 且生成了推荐问题时，run_finished 之后还会输出 follow_up_questions 作为最后一个事件。
+// 读到最后一个事件前不再读取时需调用 Close，会取消运行并等待后台的 goroutine 退出。
+type EventIterator struct {
+	q *runQueue[*AgentEvent]
+}
+
+// Next 阻塞直到有新的事件，读完最后一个事件或 Close 后返回 false
+func (it *EventIterator) Next() (*AgentEvent, bool) {
+	return it.q.next()
+}
+
+// Close 取消运行，丢弃未读取的事件，并等待后台的 goroutine 退出，可重复调用
+func (it *EventIterator) Close() {
+	it.q.close()
+}
+
+// StreamEvents 流式运行并输出类型化的事件，无需调用方再从消息流中区分正文、tool call 和 tool 结果
+func (r *Agent) StreamEvents(ctx context.Context, input []*schema.Message, options ...Option) (*EventIterator, error) {
+	q := newRunQueue(func(*AgentEvent) {})
+	h := &eventHandler{q: q}
+	err := r.startStream(ctx, input, options, h.handler(), func(runID string, cancel context.CancelFunc) {
+		q.cancel = cancel
+		h.runID = runID
+		q.push(&AgentEvent{Type: EventRunStarted, RunID: runID})
+	}, func(runID string, msg *schema.Message, err error, followUp func() []string) {
+		if err != nil {
+			q.finish(&AgentEvent{Type: EventRunError, RunID: runID, Err: err})
+			return
+		}
+		// 先输出最终结果，不等待推荐问题的生成
+		q.push(&AgentEvent{Type: EventRunFinished, RunID: runID, Message: msg})
+		if questions := followUp(); len(questions) > 0 {
+			q.finish(&AgentEvent{Type: EventFollowUpQuestions, RunID: runID, Questions: questions})
+			return
+		}
+		q.finish()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &EventIterator{q: q}, nil
+}
+
+// eventHandler 将 ChatModel 和 tool 的 callbacks 转换为事件。
+// ChatModel 的输出流在 callback 中同步读取，保证 tool call 的事件先于 tool 的结果。
+type eventHandler struct {
+	q     *runQueue[*AgentEvent]
+	runID string
+}
+
+func (h *eventHandler) emit(ctx context.Context, event *AgentEvent) {
+	event.RunID = h.runID
+	if info := runInfoFromCtx(ctx); info != nil {
+		event.Step = int(info.step.Load())
+	}
+	h.q.push(event)
+}
+
+func (h *eventHandler) handler() callbacks.Handler {
+	modelHandler := &ub.ModelCallbackHandler{
+		OnStart: func(ctx context.Context, _ *callbacks.RunInfo, _ *model.CallbackInput) context.Context {
+			h.emit(ctx, &AgentEvent{Type: EventStepStarted})
+			return ctx
+		},
+		OnEnd: func(ctx context.Context, _ *callbacks.RunInfo, output *model.CallbackOutput) context.Context {
+			if output.Message != nil {
+				h.emitDelta(ctx, output.Message, make(map[int]string))
+				h.finishStep(ctx, output.Message)
+			}
+			return ctx
+		},
+		OnEndWithStreamOutput: func(ctx context.Context, _ *callbacks.RunInfo, output *schema.StreamReader[*model.CallbackOutput]) context.Context {
+			defer output.Close()
+			var chunks []*schema.Message
+			toolCalls := make(map[int]string)
+			for {
+				o, err := output.Recv()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					// 错误会由运行结果返回
+					return ctx
+				}
+				if o.Message == nil {
+					continue
+				}
+				chunks = append(chunks, o.Message)
+				h.emitDelta(ctx, o.Message, toolCalls)
+			}
+			if len(chunks) == 0 {
+				return ctx
+			}
+			msg, err := schema.ConcatMessages(chunks)
+			if err != nil {
+				return ctx
+			}
+			h.finishStep(ctx, msg)
+			return ctx
+		},
+	}
+	toolHandler := &ub.ToolCallbackHandler{
+		OnEnd: func(ctx context.Context, info *callbacks.RunInfo, output *tool.CallbackOutput) context.Context {
+			h.emit(ctx, &AgentEvent{Type: EventToolResult, ToolCallID: compose.GetToolCallID(ctx), ToolName: info.Name, Result: output.Response})
+			return ctx
+		},
+		OnEndWithStreamOutput: func(ctx context.Context, info *callbacks.RunInfo, output *schema.StreamReader[*tool.CallbackOutput]) context.Context {
+			defer output.Close()
+			var sb strings.Builder
+			for {
+				o, err := output.Recv()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					return ctx
+				}
+				sb.WriteString(o.Response)
+			}
+			h.emit(ctx, &AgentEvent{Type: EventToolResult, ToolCallID: compose.GetToolCallID(ctx), ToolName: info.Name, Result: sb.String()})
+			return ctx
+		},
+	}
+	return ub.NewHandlerHelper().ChatModel(modelHandler).Tool(toolHandler).Handler()
+}
+
+// emitDelta toolCalls 记录已开始的 tool call，key 为 tool call 的 Index，value 为其 ID
+func (h *eventHandler) emitDelta(ctx context.Context, msg *schema.Message, toolCalls map[int]string) {
+	if msg.ReasoningContent != "" {
+		h.emit(ctx, &AgentEvent{Type: EventReasoningDelta, Delta: msg.ReasoningContent})
+	}
+	if msg.Content != "" {
+		h.emit(ctx, &AgentEvent{Type: EventTextDelta, Delta: msg.Content})
+	}
+	for i, tc := range msg.ToolCalls {
+		index := i
+		if tc.Index != nil {
+			index = *tc.Index
+		}
+		id, started := toolCalls[index]
+		if !started {
+			id = tc.ID
+			toolCalls[index] = id
+			h.emit(ctx, &AgentEvent{Type: EventToolCallStarted, ToolCallID: id, ToolName: tc.Function.Name})
+		}
+		if tc.Function.Arguments != "" {
+			h.emit(ctx, &AgentEvent{Type: EventToolCallArgsDelta, ToolCallID: id, Delta: tc.Function.Arguments})
+		}
+	}
+}
+
+func (h *eventHandler) finishStep(ctx context.Context, msg *schema.Message) {
+	for _, tc := range msg.ToolCalls {
+		h.emit(ctx, &AgentEvent{Type: EventToolCallFinished, ToolCallID: tc.ID, ToolName: tc.Function.Name, Arguments: tc.Function.Arguments})
+	}
+	event := &AgentEvent{Type: EventStepFinished, Message: msg}
+	if session, ok := GetToolSession(ctx); ok {
+		event.Session = session.State()
+	}
+	if info := runInfoFromCtx(ctx); info != nil {
+		event.Route = lastRouteDecision(ctx, int(info.step.Load()))
+	}
+	h.emit(ctx, event)
+}
@@ -0,0 +1,94 @@
+package t_eino
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+func TestStreamEvents(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		q    string
+		// want 每个事件为 "<type>@<step>[:<detail>]"，run_* 事件不属于某个 step，不记录 step
+		want []string
+	}{
+		{
+			name: "tool call",
+			q:    "hello",
+			want: []string{
+				"run_started",
+				"step_started@1",
+				"tool_call_started@1:c1 echo",
+				`tool_call_args_delta@1:{"q":"hello"}`,
+				`tool_call_finished@1:c1 {"q":"hello"}`,
+				"step_finished@1",
+				"tool_result@1:c1 echo hello",
+				"step_started@2",
+				"text_delta@2:final",
+				"step_finished@2",
+				"run_finished:final",
+			},
+		},
+		{
+			name: "tool error",
+			q:    "fail",
+			want: []string{
+				"run_started",
+				"step_started@1",
+				"tool_call_started@1:c1 echo",
+				`tool_call_args_delta@1:{"q":"fail"}`,
+				`tool_call_finished@1:c1 {"q":"fail"}`,
+				"step_finished@1",
+				"run_error:" + ErrToolExecution.Error(),
+			},
+		},
+	}
+	for _, tt := range tests {
+		for _, m := range testModels(newEventTestModel(tt.q)) {
+			t.Run(tt.name+"/"+m.name, func(t *testing.T) {
+				it, err := newEventTestAgent(t, ctx, m.model).StreamEvents(ctx, []*schema.Message{schema.UserMessage("hi")})
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer it.Close()
+				var got []string
+				for {
+					e, ok := it.Next()
+					if !ok {
+						break
+					}
+					s := fmt.Sprintf("%s@%d", e.Type, e.Step)
+					if strings.HasPrefix(string(e.Type), "run_") {
+						s = string(e.Type)
+					}
+					switch e.Type {
+					case EventToolCallStarted:
+						s += ":" + e.ToolCallID + " " + e.ToolName
+					case EventToolCallArgsDelta, EventTextDelta:
+						s += ":" + e.Delta
+					case EventToolCallFinished:
+						s += ":" + e.ToolCallID + " " + e.Arguments
+					case EventToolResult:
+						s += ":" + e.ToolCallID + " " + e.Result
+					case EventRunFinished:
+						s += ":" + e.Message.Content
+					case EventRunError:
+						if !IsToolExecutionFailed(e.Err) {
+							t.Fatalf("got run error %v", e.Err)
+						}
+						s += ":" + ErrToolExecution.Error()
+					}
+					got = append(got, s)
+				}
+				if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+					t.Fatalf("got events:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package t_eino
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+const (
+	// FollowUpQuestionsExtraKey 配置了 FollowUp 时，最终结果的 Extra 中记录推荐的问题（[]string）
+	FollowUpQuestionsExtraKey = "_agent_follow_up_questions"
+
+	suggestQuestionsToolName = "suggest_follow_up_questions"
+	defaultFollowUpCount     = 3
+)
+
+// FollowUpConfig 下一步推荐：得到最终结果后，再调用一次模型根据本次运行的上下文生成推荐的问题，
+// 问题只记录在结果的 Extra 和 follow_up_questions 事件中，不会加入对话的上下文
+type FollowUpConfig struct {
+	// Model 可选，生成问题的模型，建议使用轻量的模型，默认使用 ToolCallingModel
+	Model model.ToolCallingChatModel
+	// Count 推荐问题的数量，默认 3
+	Count int
+	// Prompt 可选，system prompt，必须包含一个 %d 作为问题数量，其它的 % 需写作 %%，默认 FollowUpPrompt
+	Prompt string
+	// MaxMessages 可选，只使用最近的 MaxMessages 条消息，默认使用全部
+	MaxMessages int
+}
+
+// followUp 生成推荐问题，通过强制调用 suggest_follow_up_questions 得到结构化的输出
+type followUp struct {
+	model       model.ToolCallingChatModel
+	count       int
+	prompt      string
+	maxMessages int
+}
+
+type suggestQuestionsArguments struct {
+	Questions []string `json:"questions"`
+}
+
+func newFollowUp(config *FollowUpConfig, defaultModel model.ToolCallingChatModel) (*followUp, error) {
+	f := &followUp{
+		model:       config.Model,
+		count:       config.Count,
+		prompt:      config.Prompt,
+		maxMessages: config.MaxMessages,
+	}
+	if f.model == nil {
+		f.model = defaultModel
+	}
+	if f.count <= 0 {
+		f.count = defaultFollowUpCount
+	}
+	if f.prompt == "" {
+		f.prompt = FollowUpPrompt
+	}
+	// 缺少 %d 或有多余的占位符时格式化结果中会出现 %!(EXTRA int=3) 等错误标记
+	prompt := fmt.Sprintf(f.prompt, f.count)
+	if strings.Contains(prompt, "%!") {
+		return nil, fmt.Errorf("follow up prompt must contain exactly one %%d for the question count, got %q", prompt)
+	}
+	f.prompt = prompt
+	var err error
+	f.model, err = f.model.WithTools([]*schema.ToolInfo{{
+		Name: suggestQuestionsToolName,
+		Desc: SuggestQuestionsToolDescription,
+		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
+			"questions": {
+				Type:     schema.Array,
+				ElemInfo: &schema.ParameterInfo{Type: schema.String},
+				Desc:     "推荐的问题，站在用户的角度提问",
+				Required: true,
+			},
+		}),
+	}})
+	if err != nil {
+		return nil, fmt.Errorf("bind follow up tool fail: %w", err)
+	}
+	return f, nil
+}
+
+// generate msgs 为本次运行的完整上下文，包含最终结果
+func (f *followUp) generate(ctx context.Context, msgs []*schema.Message) ([]string, error) {
+	if f.maxMessages > 0 && len(msgs) > f.maxMessages {
+		msgs = msgs[len(msgs)-f.maxMessages:]
+	}
+	var sb strings.Builder
+	sb.WriteString(FollowUpContextPrompt)
+	renderMessages(&sb, msgs)
+	msg, err := f.model.Generate(ctx, []*schema.Message{
+		schema.SystemMessage(f.prompt),
+		schema.UserMessage(sb.String()),
+	}, model.WithToolChoice(schema.ToolChoiceForced, suggestQuestionsToolName))
+	if err != nil {
+		return nil, err
+	}
+
+	args := &suggestQuestionsArguments{}
+	switch {
+	case len(msg.ToolCalls) > 0:
+		err = json.Unmarshal([]byte(msg.ToolCalls[0].Function.Arguments), args)
+	default:
+		// 不支持强制调用 tool 的模型可能直接输出 json
+		content := strings.TrimSpace(msg.Content)
+		if strings.HasPrefix(content, "[") {
+			err = json.Unmarshal([]byte(content), &args.Questions)
+		} else {
+			err = json.Unmarshal([]byte(content), args)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse follow up questions fail: %w", err)
+	}
+	questions := make([]string, 0, f.count)
+	for _, q := range args.Questions {
+		if q = strings.TrimSpace(q); q != "" && len(questions) < f.count {
+			questions = append(questions, q)
+		}
+	}
+	return questions, nil
+}
+
+// followUpQuestions 生成推荐问题，未配置 FollowUp 或失败时返回 nil，失败不影响运行结果
+func (r *Agent) followUpQuestions(ctx context.Context, info *runInfo, msg *schema.Message) []string {
+	if r.followUp == nil || msg == nil {
+		return nil
+	}
+	questions, err := r.followUp.generate(ctx, append(append([]*schema.Message(nil), info.messages...), msg))
+	if err != nil {
+		return nil
+	}
+	return questions
+}
+
+// withFollowUpQuestions 将推荐问题记录到结果的 Extra 中，返回的是结果的副本，不修改 state 和快照中的消息
+func withFollowUpQuestions(msg *schema.Message, questions []string) *schema.Message {
+	if msg == nil || len(questions) == 0 {
+		return msg
+	}
+	cp := *msg
+	cp.Extra = make(map[string]any, len(msg.Extra)+1)
+	for k, v := range msg.Extra {
+		cp.Extra[k] = v
+	}
+	cp.Extra[FollowUpQuestionsExtraKey] = questions
+	return &cp
+}
+
+// GetFollowUpQuestions 获取最终结果中推荐的问题
+func GetFollowUpQuestions(msg *schema.Message) []string {
+	if msg == nil {
+		return nil
+	}
+	switch questions := msg.Extra[FollowUpQuestionsExtraKey].(type) {
+	case []string:
+		return questions
+	case []any:
+		// 经过 json 序列化后的消息
+		result := make([]string, 0, len(questions))
+		for _, q := range questions {
+			if s, ok := q.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package t_eino
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+func TestNewFollowUpPrompt(t *testing.T) {
+	llm := &scriptedModel{reply: func([]*schema.Message, []*schema.ToolInfo) *schema.Message { return nil }}
+	tests := []struct {
+		name    string
+		prompt  string
+		want    string
+		wantErr bool
+	}{
+		{name: "default", want: fmt.Sprintf(FollowUpPrompt, defaultFollowUpCount)},
+		{name: "custom", prompt: "suggest %d questions", want: "suggest 3 questions"},
+		{name: "escaped percent", prompt: "suggest %d questions, 100%% related", want: "suggest 3 questions, 100% related"},
+		{name: "missing count", prompt: "suggest some questions", wantErr: true},
+		{name: "extra verb", prompt: "suggest %d questions about %s", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := newFollowUp(&FollowUpConfig{Prompt: tt.prompt}, llm)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got err %v, want err %v", err, tt.wantErr)
+			}
+			if err == nil && f.prompt != tt.want {
+				t.Fatalf("got prompt %q, want %q", f.prompt, tt.want)
+			}
+		})
+	}
+}
+
+func TestFollowUpEvents(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		// suggest 推荐问题模型的回复
+		suggest    *schema.Message
+		wantEvents string
+		wantExtra  []string
+	}{
+		{
+			name:       "questions",
+			suggest:    toolCallMessage(newToolCall("s1", suggestQuestionsToolName, `{"questions":["q1","q2"]}`)),
+			wantEvents: "run_started step_started text_delta step_finished run_finished follow_up_questions",
+			wantExtra:  []string{"q1", "q2"},
+		},
+		{
+			// 生成失败时没有 follow_up_questions 事件
+			name:       "invalid output",
+			suggest:    schema.AssistantMessage("not json", nil),
+			wantEvents: "run_started step_started text_delta step_finished run_finished",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := NewAgent(ctx, &AgentConfig{
+				ToolCallingModel: &scriptedModel{reply: func([]*schema.Message, []*schema.ToolInfo) *schema.Message {
+					return schema.AssistantMessage("answer", nil)
+				}},
+				FollowUp: &FollowUpConfig{
+					Model: &scriptedModel{reply: func([]*schema.Message, []*schema.ToolInfo) *schema.Message { return tt.suggest }},
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			iter, err := a.StreamEvents(ctx, []*schema.Message{schema.UserMessage("hi")})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer iter.Close()
+			var (
+				types     []string
+				questions []string
+			)
+			for {
+				e, ok := iter.Next()
+				if !ok {
+					break
+				}
+				types = append(types, string(e.Type))
+				if e.Type == EventFollowUpQuestions {
+					questions = e.Questions
+				}
+			}
+			if got := strings.Join(types, " "); got != tt.wantEvents {
+				t.Fatalf("got events %q, want %q", got, tt.wantEvents)
+			}
+			if fmt.Sprint(questions) != fmt.Sprint(tt.wantExtra) {
+				t.Fatalf("got questions %v, want %v", questions, tt.wantExtra)
+			}
+
+			msg, err := a.Generate(ctx, []*schema.Message{schema.UserMessage("hi")})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := GetFollowUpQuestions(msg); fmt.Sprint(got) != fmt.Sprint(tt.wantExtra) {
+				t.Fatalf("got questions in extra %v, want %v", got, tt.wantExtra)
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package t_eino
+
+import (
+	"context"
+	"strings"
+
+	"github.com/cloudwego/eino/callbacks"
+	"github.com/cloudwego/eino/components"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/components/tool/utils"
+	"github.com/cloudwego/eino/schema"
+	ub "github.com/cloudwego/eino/utils/callbacks"
+)
+
+// scriptedModel 按输入消息和绑定的 tool 返回预设的回复
+type scriptedModel struct {
+	tools []*schema.ToolInfo
+	reply func(in []*schema.Message, tools []*schema.ToolInfo) *schema.Message
+	// onCall 可选，每次调用时传入本次调用的 option
+	onCall func(o *model.Options)
+}
+
+func (m *scriptedModel) Generate(ctx context.Context, in []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	o := model.GetCommonOptions(&model.Options{Tools: m.tools}, opts...)
+	if m.onCall != nil {
+		m.onCall(o)
+	}
+	return m.reply(in, o.Tools), nil
+}
+
+func (m *scriptedModel) Stream(ctx context.Context, in []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	msg, err := m.Generate(ctx, in, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return schema.StreamReaderFromArray([]*schema.Message{msg}), nil
+}
+
+func (m *scriptedModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	return &scriptedModel{tools: tools, reply: m.reply, onCall: m.onCall}, nil
+}
+
+// callbackModel 与 ark、openai 等真实的模型一样自己触发 callbacks，回复与 scriptedModel 相同
+type callbackModel struct {
+	*scriptedModel
+}
+
+func (m *callbackModel) GetType() string {
+	return "Callback"
+}
+
+func (m *callbackModel) IsCallbacksEnabled() bool {
+	return true
+}
+
+func (m *callbackModel) Generate(ctx context.Context, in []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	ctx = callbacks.EnsureRunInfo(ctx, m.GetType(), components.ComponentOfChatModel)
+	ctx = callbacks.OnStart(ctx, &model.CallbackInput{Messages: in})
+	msg, err := m.scriptedModel.Generate(ctx, in, opts...)
+	if err != nil {
+		callbacks.OnError(ctx, err)
+		return nil, err
+	}
+	callbacks.OnEnd(ctx, &model.CallbackOutput{Message: msg})
+	return msg, nil
+}
+
+func (m *callbackModel) Stream(ctx context.Context, in []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	ctx = callbacks.EnsureRunInfo(ctx, m.GetType(), components.ComponentOfChatModel)
+	ctx = callbacks.OnStart(ctx, &model.CallbackInput{Messages: in})
+	msg, err := m.scriptedModel.Generate(ctx, in, opts...)
+	if err != nil {
+		callbacks.OnError(ctx, err)
+		return nil, err
+	}
+	_, sr := callbacks.OnEndWithStreamOutput(ctx, schema.StreamReaderFromArray([]*model.CallbackOutput{{Message: msg}}))
+	return schema.StreamReaderWithConvert(sr, func(o *model.CallbackOutput) (*schema.Message, error) {
+		return o.Message, nil
+	}), nil
+}
+
+func (m *callbackModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	return &callbackModel{scriptedModel: &scriptedModel{tools: tools, reply: m.reply, onCall: m.onCall}}, nil
+}
+
+type testModel struct {
+	name  string
+	model model.ToolCallingChatModel
+}
+
+// testModels 同一个 scriptedModel 的两种实现，分别由模型节点和模型自己触发 callbacks
+func testModels(m *scriptedModel) []testModel {
+	return []testModel{{name: "scripted", model: m}, {name: "callbacks", model: &callbackModel{scriptedModel: m}}}
+}
+
+// modelCallbackCounter 统计 ChatModel 触发的 callbacks
+type modelCallbackCounter struct {
+	start, end int
+}
+
+// context 返回带有统计 handler 的 ctx
+func (c *modelCallbackCounter) context(ctx context.Context) context.Context {
+	handler := &ub.ModelCallbackHandler{
+		OnStart: func(ctx context.Context, _ *callbacks.RunInfo, _ *model.CallbackInput) context.Context {
+			c.start++
+			return ctx
+		},
+		OnEnd: func(ctx context.Context, _ *callbacks.RunInfo, _ *model.CallbackOutput) context.Context {
+			c.end++
+			return ctx
+		},
+		OnEndWithStreamOutput: func(ctx context.Context, _ *callbacks.RunInfo, output *schema.StreamReader[*model.CallbackOutput]) context.Context {
+			output.Close()
+			c.end++
+			return ctx
+		},
+	}
+	return callbacks.InitCallbacks(ctx, nil, ub.NewHandlerHelper().ChatModel(handler).Handler())
+}
+
+// newReplyModel 总是回答 content
+func newReplyModel(content string) *scriptedModel {
+	return &scriptedModel{reply: func(in []*schema.Message, tools []*schema.ToolInfo) *schema.Message {
+		return schema.AssistantMessage(content, nil)
+	}}
+}
+
+type testToolInput struct {
+	Q string `json:"q"`
+}
+
+// newTestTool 返回 "<name> got <q>" 的 tool
+func newTestTool(name, desc string) tool.BaseTool {
+	tl, err := utils.InferTool(name, desc, func(ctx context.Context, in testToolInput) (string, error) {
+		return name + " got " + in.Q, nil
+	})
+	if err != nil {
+		panic(err)
+	}
+	return tl
+}
+
+// newFuncTool 执行 fn 的 tool，参数与 newTestTool 相同
+func newFuncTool(name string, fn func(ctx context.Context, q string) (string, error)) tool.BaseTool {
+	tl, err := utils.InferTool(name, name, func(ctx context.Context, in testToolInput) (string, error) {
+		return fn(ctx, in.Q)
+	})
+	if err != nil {
+		panic(err)
+	}
+	return tl
+}
+
+func toolCallMessage(calls ...schema.ToolCall) *schema.Message {
+	return schema.AssistantMessage("", calls)
+}
+
+func newToolCall(id, name, args string) schema.ToolCall {
+	return schema.ToolCall{ID: id, Function: schema.FunctionCall{Name: name, Arguments: args}}
+}
+
+// toolResults 按顺序拼接 tool 的结果，格式为 "<tool_call_id>=<content>"
+func toolResults(msgs []*schema.Message) string {
+	var results []string
+	for _, msg := range msgs {
+		if msg.Role == schema.Tool {
+			results = append(results, msg.ToolCallID+"="+msg.Content)
+		}
+	}
+	return strings.Join(results, "; ")
+}
+
+// dumpMessages 按顺序拼接消息，格式为 "<role>:<content>"
+func dumpMessages(msgs []*schema.Message) string {
+	parts := make([]string, 0, len(msgs))
+	for _, msg := range msgs {
+		parts = append(parts, string(msg.Role)+":"+msg.Content)
+	}
+	return strings.Join(parts, " ")
+}
+
+// oneTokenizer 每条消息计为 1 个 token
+var oneTokenizer = TokenizerFunc(func(ctx context.Context, msg *schema.Message) int { return 1 })
+
+func toolNames(tools []*schema.ToolInfo) []string {
+	names := make([]string, 0, len(tools))
+	for _, info := range tools {
+		names = append(names, info.Name)
+	}
+	return names
+}
@@ -0,0 +1,251 @@
+package t_eino
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+const (
+	RewriterSummary = "summary"
+
+	// SummaryExtraKey 摘要消息的 Extra 中标记为 true
+	SummaryExtraKey = "_agent_summary"
+
+	defaultSummaryCacheSize = 128
+)
+
+// SummaryConfig 摘要记忆的配置
+type SummaryConfig struct {
+	// Model 生成摘要的模型，可以使用比主模型更便宜的模型
+	Model model.BaseChatModel
+	// TriggerTokens 除 system message 和固定的消息外的消息（包含已有的摘要）超过该 token 数时触发总结
+	TriggerTokens int
+	// KeepTokens 总结后保留的最近消息的 token 数，默认 TriggerTokens 的一半，最新的一组消息总会保留
+	KeepTokens int
+	// Tokenizer 可选，默认 EstimateTokenizer
+	Tokenizer Tokenizer
+	// Prompt 可选，生成摘要的 system prompt，默认 SummaryPrompt
+	Prompt string
+	// CacheSize 可选，缓存的摘要数量，默认 128
+	CacheSize int
+	// OnError 可选，总结失败时调用，此时消息保持不变
+	OnError func(ctx context.Context, err error)
+}
+
+// summaryRewriter 将最旧的一段消息总结为一条摘要消息，摘要按被总结的消息缓存，
+// 已有摘要时只总结新增的部分（增量总结），避免每次调用 ChatModel 前都重新总结
+type summaryRewriter struct {
+	config    SummaryConfig
+	tokenizer Tokenizer
+	cache     *lruCache[string] //按被总结的消息缓存摘要
+}
+
+// NewSummaryRewriter 创建摘要记忆的 MessageRewriter：消息超过 TriggerTokens 时，除最近 KeepTokens 的消息外，
+// 更早的消息（连同已有的摘要）被总结为一条固定的摘要消息，放在开头的 system message 之后。
+// assistant 的 tool call 与对应的 tool 结果不会被拆开，固定的消息（PinMessage）保留原文，不会被总结。
+func NewSummaryRewriter(config *SummaryConfig) (MessageModifier, error) {
+	if config.Model == nil {
+		return nil, fmt.Errorf("summary model is required")
+	}
+	if config.TriggerTokens <= 0 {
+		return nil, fmt.Errorf("trigger tokens must be positive")
+	}
+	s := &summaryRewriter{config: *config, tokenizer: config.Tokenizer}
+	if s.config.KeepTokens <= 0 {
+		s.config.KeepTokens = config.TriggerTokens / 2
+	}
+	if s.config.KeepTokens >= s.config.TriggerTokens {
+		return nil, fmt.Errorf("keep tokens must be less than trigger tokens")
+	}
+	if s.config.Prompt == "" {
+		s.config.Prompt = SummaryPrompt
+	}
+	if s.tokenizer == nil {
+		s.tokenizer = EstimateTokenizer
+	}
+	size := config.CacheSize
+	if size <= 0 {
+		size = defaultSummaryCacheSize
+	}
+	s.cache = newLRUCache[string](size)
+	return s.rewrite, nil
+}
+
+func (s *summaryRewriter) rewrite(ctx context.Context, input []*schema.Message) []*schema.Message {
+	var (
+		system  []*schema.Message
+		summary *schema.Message
+		history []*schema.Message
+	)
+	for _, msg := range input {
+		switch {
+		case IsSummaryMessage(msg):
+			summary = msg
+		case msg.Role == schema.System:
+			system = append(system, msg)
+		default:
+			history = append(history, msg)
+		}
+	}
+	// 固定的消息（PinMessage）与 system message 一样不计入触发的 token 数，也不会被总结
+	groups := groupMessages(history)
+	tokens := func(g messageGroup) int {
+		var n int
+		for _, msg := range history[g.start:g.end] {
+			n += s.tokenizer.CountTokens(ctx, msg)
+		}
+		return n
+	}
+	total := 0
+	if summary != nil {
+		total += s.tokenizer.CountTokens(ctx, summary)
+	}
+	for _, g := range groups {
+		if !g.pinned {
+			total += tokens(g)
+		}
+	}
+	if total <= s.config.TriggerTokens {
+		return input
+	}
+
+	// 从最新的一组往前保留 KeepTokens，之前的消息被总结
+	keep, used := len(groups), 0
+	for i := len(groups) - 1; i >= 0; i-- {
+		if groups[i].pinned {
+			keep = i
+			continue
+		}
+		n := tokens(groups[i])
+		if used+n > s.config.KeepTokens && used > 0 {
+			break
+		}
+		used += n
+		keep = i
+	}
+	var old, pinned []*schema.Message
+	for _, g := range groups[:keep] {
+		if g.pinned {
+			pinned = append(pinned, history[g.start:g.end]...)
+		} else {
+			old = append(old, history[g.start:g.end]...)
+		}
+	}
+	if len(old) == 0 {
+		return input
+	}
+	content, err := s.summarize(ctx, summary, old)
+	if err != nil {
+		if s.config.OnError != nil {
+			s.config.OnError(ctx, err)
+		}
+		return input
+	}
+
+	recent := history[groups[keep].start:]
+	result := make([]*schema.Message, 0, len(system)+1+len(pinned)+len(recent))
+	result = append(result, system...)
+	result = append(result, newSummaryMessage(content))
+	result = append(result, pinned...)
+	return append(result, recent...)
+}
+
+// summarize 按消息计算前缀哈希，优先使用缓存中最长前缀的摘要，只总结其后新增的消息
+func (s *summaryRewriter) summarize(ctx context.Context, previous *schema.Message, msgs []*schema.Message) (string, error) {
+	prev := ""
+	if previous != nil {
+		prev = strings.TrimPrefix(previous.Content, SummaryMessagePrompt)
+	}
+	keys := make([]string, len(msgs)+1)
+	keys[0] = hashSummaryKey("", prev)
+	for i, msg := range msgs {
+		var sb strings.Builder
+		renderMessages(&sb, []*schema.Message{msg})
+		keys[i+1] = hashSummaryKey(keys[i], sb.String())
+	}
+	if content, ok := s.cache.get(keys[len(msgs)]); ok {
+		return content, nil
+	}
+	start := 0
+	for i := len(msgs) - 1; i > 0; i-- {
+		if content, ok := s.cache.get(keys[i]); ok {
+			prev, start = content, i
+			break
+		}
+	}
+
+	var sb strings.Builder
+	if prev != "" {
+		sb.WriteString(SummaryPreviousPrompt)
+		sb.WriteString("\n")
+		sb.WriteString(prev)
+		sb.WriteString("\n\n")
+	}
+	sb.WriteString(SummaryContextPrompt)
+	renderMessages(&sb, msgs[start:])
+	// 在模型节点的 pre handler 中运行，去掉本次运行的 callbacks，摘要的输出不能混入事件流
+	msg, err := s.config.Model.Generate(withoutCallbacks(ctx), []*schema.Message{
+		schema.SystemMessage(s.config.Prompt),
+		schema.UserMessage(sb.String()),
+	})
+	if err != nil {
+		return "", fmt.Errorf("summarize fail: %w", err)
+	}
+	content := strings.TrimSpace(msg.Content)
+	if content == "" {
+		return "", fmt.Errorf("summarize fail: %w", errEmptyResponse)
+	}
+	s.cache.put(keys[len(msgs)], content)
+	return content, nil
+}
+
+func hashSummaryKey(prev, content string) string {
+	h := sha256.New()
+	h.Write([]byte(prev))
+	h.Write([]byte{0})
+	h.Write([]byte(content))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func newSummaryMessage(content string) *schema.Message {
+	msg := schema.SystemMessage(SummaryMessagePrompt + content)
+	msg.Extra = map[string]any{SummaryExtraKey: true, PinnedExtraKey: true}
+	return msg
+}
+
+// IsSummaryMessage 是否为 NewSummaryRewriter 生成的摘要消息
+func IsSummaryMessage(msg *schema.Message) bool {
+	v, _ := msg.Extra[SummaryExtraKey].(bool)
+	return v
+}
+
+// summaryRewriterConfig 定义文件中 summary rewriter 的 config
+type summaryRewriterConfig struct {
+	Model         ModelDefinition `json:"model"`
+	TriggerTokens int             `json:"trigger_tokens"`
+	KeepTokens    int             `json:"keep_tokens"`
+	Prompt        string          `json:"prompt"`
+}
+
+func init() {
+	_ = DefaultRegistry.RegisterRewriter(RewriterSummary, func(ctx context.Context, registry *Registry, config map[string]any) (MessageModifier, error) {
+		c, err := DecodeConfig[summaryRewriterConfig](config)
+		if err != nil {
+			return nil, fmt.Errorf("rewriter %s: %w", RewriterSummary, err)
+		}
+		if err = c.Model.validate("model"); err != nil {
+			return nil, fmt.Errorf("rewriter %s: %w", RewriterSummary, err)
+		}
+		llm, err := registry.ChatModel(ctx, c.Model.config())
+		if err != nil {
+			return nil, err
+		}
+		return NewSummaryRewriter(&SummaryConfig{Model: llm, TriggerTokens: c.TriggerTokens, KeepTokens: c.KeepTokens, Prompt: c.Prompt})
+	})
+}
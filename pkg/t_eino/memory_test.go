@@ -0,0 +1,242 @@
+package t_eino
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// summaryModel 依次返回 S1、S2……，prompts 记录每次收到的总结内容
+type summaryModel struct {
+	scriptedModel
+	prompts []string
+}
+
+func newSummaryModel() *summaryModel {
+	m := &summaryModel{}
+	m.reply = func(in []*schema.Message, tools []*schema.ToolInfo) *schema.Message {
+		m.prompts = append(m.prompts, in[1].Content)
+		return schema.AssistantMessage(fmt.Sprintf("S%d", len(m.prompts)), nil)
+	}
+	return m
+}
+
+func summaryTestMessages() []*schema.Message {
+	return []*schema.Message{
+		schema.SystemMessage("sys"),
+		schema.UserMessage("q1"),
+		schema.AssistantMessage("r1", nil),
+		schema.UserMessage("q2"),
+		schema.AssistantMessage("r2", nil),
+		schema.UserMessage("q3"),
+	}
+}
+
+func TestSummaryRewriter(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name      string
+		model     model.BaseChatModel
+		input     []*schema.Message
+		want      string
+		wantError bool
+	}{
+		{
+			name:  "below trigger",
+			model: newSummaryModel(),
+			input: summaryTestMessages()[:4],
+			want:  "system:sys user:q1 assistant:r1 user:q2",
+		},
+		{
+			name:  "summarize old messages",
+			model: newSummaryModel(),
+			input: summaryTestMessages(),
+			want:  "system:sys system:" + SummaryMessagePrompt + "S1 assistant:r2 user:q3",
+		},
+		{
+			name:  "pinned messages kept",
+			model: newSummaryModel(),
+			input: []*schema.Message{
+				schema.SystemMessage("sys"),
+				PinMessage(schema.UserMessage("file")),
+				schema.UserMessage("q1"),
+				toolCallMessage(newToolCall("a", "t", "{}")),
+				PinMessage(schema.ToolMessage("ra", "a")),
+				schema.AssistantMessage("r1", nil),
+				schema.UserMessage("q2"),
+				schema.AssistantMessage("r2", nil),
+				schema.UserMessage("q3"),
+			},
+			want: "system:sys system:" + SummaryMessagePrompt + "S1 user:file assistant: tool:ra assistant:r2 user:q3",
+		},
+		{
+			name:      "model error",
+			model:     &failingModel{err: errors.New("down")},
+			input:     summaryTestMessages(),
+			want:      "system:sys user:q1 assistant:r1 user:q2 assistant:r2 user:q3",
+			wantError: true,
+		},
+		{
+			name:      "empty summary",
+			model:     &failingModel{},
+			input:     summaryTestMessages(),
+			want:      "system:sys user:q1 assistant:r1 user:q2 assistant:r2 user:q3",
+			wantError: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotErr error
+			rw, err := NewSummaryRewriter(&SummaryConfig{
+				Model:         tt.model,
+				TriggerTokens: 3,
+				KeepTokens:    2,
+				Tokenizer:     oneTokenizer,
+				OnError:       func(ctx context.Context, err error) { gotErr = err },
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			out := rw(ctx, tt.input)
+			if got := dumpMessages(out); got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+			if (gotErr != nil) != tt.wantError {
+				t.Fatalf("got error %v, want error %v", gotErr, tt.wantError)
+			}
+			if err = checkPinned(collectPinned(tt.input), out); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestSummaryRewriterIncremental(t *testing.T) {
+	ctx := context.Background()
+	m := newSummaryModel()
+	rw, err := NewSummaryRewriter(&SummaryConfig{Model: m, TriggerTokens: 3, KeepTokens: 2, Tokenizer: oneTokenizer})
+	if err != nil {
+		t.Fatal(err)
+	}
+	history := summaryTestMessages()
+	more := []*schema.Message{schema.AssistantMessage("r3", nil), schema.UserMessage("q4")}
+
+	out := rw(ctx, history)
+	tests := []struct {
+		name  string
+		input []*schema.Message
+		want  string
+		// wantPrompt 本次总结的内容包含的消息，为空时期望命中缓存
+		wantPrompt []string
+		skipPrompt []string
+	}{
+		{
+			name:  "same messages hit cache",
+			input: history,
+			want:  "system:sys system:" + SummaryMessagePrompt + "S1 assistant:r2 user:q3",
+		},
+		{
+			// 已有摘要时只总结新增的部分
+			name:       "summarize with previous summary",
+			input:      append(append([]*schema.Message(nil), out...), more...),
+			want:       "system:sys system:" + SummaryMessagePrompt + "S2 assistant:r3 user:q4",
+			wantPrompt: []string{SummaryPreviousPrompt, "S1", "r2", "q3"},
+			skipPrompt: []string{"q1", "r1", "q2"},
+		},
+		{
+			// 原始历史变长时复用缓存中最长前缀的摘要
+			name:       "reuse cached prefix",
+			input:      append(append([]*schema.Message(nil), history...), more...),
+			want:       "system:sys system:" + SummaryMessagePrompt + "S3 assistant:r3 user:q4",
+			wantPrompt: []string{SummaryPreviousPrompt, "S1", "r2", "q3"},
+			skipPrompt: []string{"q1", "r1", "q2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := len(m.prompts)
+			if got := dumpMessages(rw(ctx, tt.input)); got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+			if len(tt.wantPrompt) == 0 {
+				if len(m.prompts) != calls {
+					t.Fatalf("got %d summary calls, want cache hit", len(m.prompts)-calls)
+				}
+				return
+			}
+			if len(m.prompts) != calls+1 {
+				t.Fatalf("got %d summary calls, want 1", len(m.prompts)-calls)
+			}
+			prompt := m.prompts[len(m.prompts)-1]
+			for _, s := range tt.wantPrompt {
+				if !strings.Contains(prompt, s) {
+					t.Fatalf("prompt %q does not contain %q", prompt, s)
+				}
+			}
+			for _, s := range tt.skipPrompt {
+				if strings.Contains(prompt, s) {
+					t.Fatalf("prompt %q contains %q", prompt, s)
+				}
+			}
+		})
+	}
+}
+
+func TestNewSummaryRewriterInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		config SummaryConfig
+	}{
+		{name: "no model", config: SummaryConfig{TriggerTokens: 10}},
+		{name: "no trigger tokens", config: SummaryConfig{Model: newSummaryModel()}},
+		{name: "keep tokens too large", config: SummaryConfig{Model: newSummaryModel(), TriggerTokens: 10, KeepTokens: 10}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewSummaryRewriter(&tt.config); err == nil {
+				t.Fatal("got nil error")
+			}
+		})
+	}
+}
+
+func TestSummaryRewriterInAgent(t *testing.T) {
+	ctx := context.Background()
+	m := newSummaryModel()
+	rw, err := NewSummaryRewriter(&SummaryConfig{Model: &callbackModel{scriptedModel: &m.scriptedModel}, TriggerTokens: 3, KeepTokens: 2, Tokenizer: oneTokenizer})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := NewAgent(ctx, &AgentConfig{ToolCallingModel: newReplyModel("answer"), MessageRewriter: rw})
+	if err != nil {
+		t.Fatal(err)
+	}
+	it, err := a.StreamEvents(ctx, summaryTestMessages())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer it.Close()
+	// 摘要模型自己触发 callbacks，事件中只能有主模型的一步
+	var got []string
+	for {
+		e, ok := it.Next()
+		if !ok {
+			break
+		}
+		switch e.Type {
+		case EventStepStarted, EventStepFinished:
+			got = append(got, string(e.Type))
+		case EventTextDelta:
+			got = append(got, string(e.Type)+":"+e.Delta)
+		}
+	}
+	want := "step_started text_delta:answer step_finished"
+	if strings.Join(got, " ") != want || len(m.prompts) != 1 {
+		t.Fatalf("got events %v after %d summaries, want %s after 1 summary", got, len(m.prompts), want)
+	}
+}
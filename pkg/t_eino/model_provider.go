@@ -0,0 +1,154 @@
+package t_eino
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/eino-ext/components/model/ark"
+	"github.com/cloudwego/eino-ext/components/model/ollama"
+	"github.com/cloudwego/eino-ext/components/model/openai"
+	"github.com/cloudwego/eino/components/model"
+)
+
+const (
+	ModelProviderArk    = "ark"
+	ModelProviderOpenAI = "openai" //OpenAI 及兼容 OpenAI 接口的服务，BaseURL 指向服务地址
+	ModelProviderOllama = "ollama"
+)
+
+// ModelConfig 创建 ChatModel 的配置，各 provider 共用
+type ModelConfig struct {
+	Provider string
+	Model    string
+	APIKey   string
+	BaseURL  string
+
+	// Timeout 和 Headers 作用于请求使用的 http.Client，都不设置时使用 provider 的默认值
+	Timeout time.Duration
+	Headers map[string]string
+	// HTTPClient 可选，设置后 Timeout 和 Headers 作用于其副本
+	HTTPClient *http.Client
+
+	Temperature *float32
+	TopP        *float32
+	MaxTokens   *int
+}
+
+// ModelProvider 按 ModelConfig 创建 ChatModel
+type ModelProvider func(ctx context.Context, config *ModelConfig) (model.ToolCallingChatModel, error)
+
+var (
+	modelProvidersLock sync.RWMutex
+	modelProviders     = map[string]ModelProvider{
+		ModelProviderArk:    newArkChatModel,
+		ModelProviderOpenAI: newOpenAIChatModel,
+		ModelProviderOllama: newOllamaChatModel,
+	}
+)
+
+// RegisterModelProvider 注册全局的 provider，重名时返回错误；只在某个 Registry 中生效的使用 Registry.RegisterModelProvider
+func RegisterModelProvider(name string, provider ModelProvider) error {
+	modelProvidersLock.Lock()
+	defer modelProvidersLock.Unlock()
+	if _, ok := modelProviders[name]; ok {
+		return fmt.Errorf("model provider %s is already registered", name)
+	}
+	modelProviders[name] = provider
+	return nil
+}
+
+// NewChatModel 按 Provider 创建 ChatModel
+func NewChatModel(ctx context.Context, config *ModelConfig) (model.ToolCallingChatModel, error) {
+	modelProvidersLock.RLock()
+	provider, ok := modelProviders[config.Provider]
+	modelProvidersLock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unsupported model provider %s", config.Provider)
+	}
+	return provider(ctx, config)
+}
+
+// httpClient Timeout、Headers、HTTPClient 都未设置时返回 nil，使用 provider 的默认 client
+func (c *ModelConfig) httpClient() *http.Client {
+	if c.HTTPClient == nil && c.Timeout == 0 && len(c.Headers) == 0 {
+		return nil
+	}
+	client := &http.Client{}
+	if c.HTTPClient != nil {
+		*client = *c.HTTPClient
+	}
+	if c.Timeout > 0 {
+		client.Timeout = c.Timeout
+	}
+	if len(c.Headers) > 0 {
+		base := client.Transport
+		if base == nil {
+			base = http.DefaultTransport
+		}
+		client.Transport = &headerTransport{base: base, headers: c.Headers}
+	}
+	return client
+}
+
+// headerTransport 为每个请求加上固定的 header
+type headerTransport struct {
+	base    http.RoundTripper
+	headers map[string]string
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	return t.base.RoundTrip(req)
+}
+
+func newArkChatModel(ctx context.Context, c *ModelConfig) (model.ToolCallingChatModel, error) {
+	return ark.NewChatModel(ctx, &ark.ChatModelConfig{
+		APIKey:      c.APIKey,
+		Model:       c.Model,
+		BaseURL:     c.BaseURL,
+		HTTPClient:  c.httpClient(),
+		Temperature: c.Temperature,
+		TopP:        c.TopP,
+		MaxTokens:   c.MaxTokens,
+	})
+}
+
+func newOpenAIChatModel(ctx context.Context, c *ModelConfig) (model.ToolCallingChatModel, error) {
+	return openai.NewChatModel(ctx, &openai.ChatModelConfig{
+		APIKey:      c.APIKey,
+		Model:       c.Model,
+		BaseURL:     c.BaseURL,
+		HTTPClient:  c.httpClient(),
+		Temperature: c.Temperature,
+		TopP:        c.TopP,
+		MaxTokens:   c.MaxTokens,
+	})
+}
+
+func newOllamaChatModel(ctx context.Context, c *ModelConfig) (model.ToolCallingChatModel, error) {
+	config := &ollama.ChatModelConfig{
+		BaseURL:    c.BaseURL,
+		Model:      c.Model,
+		HTTPClient: c.httpClient(),
+	}
+	if c.Temperature != nil || c.TopP != nil || c.MaxTokens != nil {
+		// 零值不会被发送，未设置的参数使用 ollama 的默认值
+		config.Options = &ollama.Options{}
+		if c.Temperature != nil {
+			config.Options.Temperature = *c.Temperature
+		}
+		if c.TopP != nil {
+			config.Options.TopP = *c.TopP
+		}
+		if c.MaxTokens != nil {
+			config.Options.NumPredict = *c.MaxTokens
+		}
+	}
+	return ollama.NewChatModel(ctx, config)
+}
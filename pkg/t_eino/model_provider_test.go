@@ -0,0 +1,136 @@
+package t_eino
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+func TestNewChatModel(t *testing.T) {
+	ctx := context.Background()
+	type request struct {
+		path        string
+		team        string
+		temperature any
+	}
+	var got request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var payload map[string]any
+		_ = json.Unmarshal(body, &payload)
+		got = request{path: r.URL.Path, team: r.Header.Get("X-Team"), temperature: payload["temperature"]}
+		if options, ok := payload["options"].(map[string]any); ok {
+			got.temperature = options["temperature"]
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/api/chat") {
+			_, _ = w.Write([]byte(`{"model":"m","created_at":"2024-01-01T00:00:00Z","message":{"role":"assistant","content":"hi from ollama"},"done":true}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":"1","object":"chat.completion","created":1,"model":"m","choices":[{"index":0,"message":{"role":"assistant","content":"hi"},"finish_reason":"stop"}],"usage":{"prompt_tokens":1,"completion_tokens":1,"total_tokens":2}}`))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		provider string
+		wantPath string
+		want     string
+	}{
+		{provider: ModelProviderOpenAI, wantPath: "/chat/completions", want: "hi"},
+		{provider: ModelProviderArk, wantPath: "/chat/completions", want: "hi"},
+		{provider: ModelProviderOllama, wantPath: "/api/chat", want: "hi from ollama"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			temperature := float32(0.5)
+			m, err := NewChatModel(ctx, &ModelConfig{
+				Provider:    tt.provider,
+				Model:       "m",
+				APIKey:      "k",
+				BaseURL:     srv.URL,
+				Timeout:     5 * time.Second,
+				Headers:     map[string]string{"X-Team": "t1"},
+				Temperature: &temperature,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			counter := &modelCallbackCounter{}
+			msg, err := m.Generate(counter.context(ctx), []*schema.Message{schema.UserMessage("hello")})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if msg.Content != tt.want {
+				t.Fatalf("got %q, want %q", msg.Content, tt.want)
+			}
+			// 真实的模型自己触发 callbacks，callbackModel 模拟这一行为
+			if counter.start != 1 || counter.end != 1 {
+				t.Fatalf("got callbacks %+v, want one start and one end", *counter)
+			}
+			want := request{path: tt.wantPath, team: "t1", temperature: 0.5}
+			if got != want {
+				t.Fatalf("got request %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestCallbackModel(t *testing.T) {
+	ctx := context.Background()
+	m := &callbackModel{scriptedModel: newReplyModel("hi")}
+	tests := []struct {
+		name string
+		call func(ctx context.Context) (string, error)
+	}{
+		{
+			name: "generate",
+			call: func(ctx context.Context) (string, error) {
+				msg, err := m.Generate(ctx, []*schema.Message{schema.UserMessage("hello")})
+				if err != nil {
+					return "", err
+				}
+				return msg.Content, nil
+			},
+		},
+		{
+			name: "stream",
+			call: func(ctx context.Context) (string, error) {
+				sr, err := m.Stream(ctx, []*schema.Message{schema.UserMessage("hello")})
+				if err != nil {
+					return "", err
+				}
+				msgs, err := readAll(sr)
+				if err != nil {
+					return "", err
+				}
+				return msgs[0].Content, nil
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counter := &modelCallbackCounter{}
+			got, err := tt.call(counter.context(ctx))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != "hi" || counter.start != 1 || counter.end != 1 {
+				t.Fatalf("got %q with callbacks %+v, want hi with one start and one end", got, *counter)
+			}
+		})
+	}
+}
+
+func TestNewChatModelUnknownProvider(t *testing.T) {
+	_, err := NewChatModel(context.Background(), &ModelConfig{Provider: "nope"})
+	if err == nil || !strings.Contains(err.Error(), "unsupported model provider nope") {
+		t.Fatalf("got %v, want unsupported provider error", err)
+	}
+}
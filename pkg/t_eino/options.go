@@ -2,38 +2,93 @@ package t_eino
 
 import (
 	"context"
-	"github.com/cloudwego/eino/components/model"
 	"github.com/cloudwego/eino/components/tool"
-	"github.com/cloudwego/eino/compose"
 	"github.com/cloudwego/eino/flow/agent"
 	"github.com/cloudwego/eino/schema"
 )
 
+// Option 作用于单次运行，需要修改本次运行独享的 ToolSession 时返回 withSessionOption 生成的 option
 type Option func(agent *Agent) ([]agent.AgentOption, error)
 
-// 注入额外的tool，不会覆盖config的tools
+// sessionOptions 运行开始前依次作用于本次运行的 ToolSession
+type sessionOptions struct {
+	fns []func(s *ToolSession) error
+}
+
+func withSessionOption(fn func(s *ToolSession) error) []agent.AgentOption {
+	return []agent.AgentOption{agent.WrapImplSpecificOptFn(func(o *sessionOptions) {
+		o.fns = append(o.fns, fn)
+	})}
+}
+
+// 注入额外的tool，与config的tools、special tools、已有的额外tool或skill的tool重名时运行会返回错误
+// 额外的tool只登记在本次运行的 ToolSession 中，被 special_get_tool 获取后才会绑定到 ChatModel 并可被 ToolsNode 执行
 func WithTools(ctx context.Context, tools ...tool.BaseTool) (Option, error) {
 	m, err := toolsToMap(ctx, tools)
 	if err != nil {
 		return nil, err
 	}
+	if err = checkToolNames(m, nil, "extra tool"); err != nil {
+		return nil, err
+	}
 
 	o := func(a *Agent) ([]agent.AgentOption, error) {
-		a.toolList.extraToolsMap = m
-		tools := a.toolList.GetTools()
-		toolInfos := make([]*schema.ToolInfo, 0, len(tools))
-		for _, tl := range tools {
-			info, err := tl.Info(ctx)
-			if err != nil {
-				return nil, err
+		return withSessionOption(func(s *ToolSession) error {
+			if err := s.addExtraTools(ctx, m); err != nil {
+				return err
 			}
-			toolInfos = append(toolInfos, info)
-		}
-		opts := make([]agent.AgentOption, 2)
-		opts[0] = agent.WithComposeOptions(compose.WithChatModelOption(model.WithTools(toolInfos)))
-		opts[1] = agent.WithComposeOptions(compose.WithToolsNodeOption(compose.WithToolList(tools...)))
-		return opts, nil
+			// 设置了 ToolEmbedder 时在注入时计算向量，已缓存的不会重复计算
+			if r := s.toolList.retriever; r != nil {
+				if _, err := r.buildIndex(ctx, m); err != nil {
+					return err
+				}
+			}
+			return nil
+		}), nil
 	}
 
 	return o, nil
 }
+
+// WithToolNames 从 registry 按名称创建额外的 tool 并注入，registry 为 nil 时使用 DefaultRegistry
+func WithToolNames(ctx context.Context, registry *Registry, specs ...ToolSpec) (Option, error) {
+	if registry == nil {
+		registry = DefaultRegistry
+	}
+	tools, err := registry.Tools(ctx, specs...)
+	if err != nil {
+		return nil, err
+	}
+	return WithTools(ctx, tools...)
+}
+
+// WithSkills 为本次运行注入顶层 skill，需设置 AgentConfig.Skills 或 DynamicSkills，
+// 与已有 skill 重名时返回错误。恢复运行时需重新注入。
+func WithSkills(skills ...*Skill) Option {
+	return func(a *Agent) ([]agent.AgentOption, error) {
+		return withSessionOption(func(s *ToolSession) error {
+			return s.AddSkills(skills...)
+		}), nil
+	}
+}
+
+// withMessages 每次 state 中的消息更新后调用 fn，fn 在运行的 goroutine 中同步调用
+func withMessages(fn func(msgs []*schema.Message)) Option {
+	return func(a *Agent) ([]agent.AgentOption, error) {
+		return withSessionOption(func(s *ToolSession) error {
+			s.onMessages = append(s.onMessages, fn)
+			return nil
+		}), nil
+	}
+}
+
+// WithRunID 指定本次运行的 ID，设置了 CheckPointStore 时运行状态按该 ID 保存，进程崩溃后可用同一 ID 调用 Agent.Resume 继续。
+// 不指定时随机生成，可从 RunError / ApprovalRequiredError 中获取。
+func WithRunID(runID string) Option {
+	return func(a *Agent) ([]agent.AgentOption, error) {
+		return withSessionOption(func(s *ToolSession) error {
+			s.runID = runID
+			return nil
+		}), nil
+	}
+}
@@ -0,0 +1,34 @@
+package t_eino
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/flow/agent"
+	"github.com/cloudwego/eino/schema"
+)
+
+func TestOption(t *testing.T) {
+	ctx := context.Background()
+	var temperature *float32
+	llm := newReplyModel("done")
+	llm.onCall = func(o *model.Options) { temperature = o.Temperature }
+	a, err := NewAgent(ctx, &AgentConfig{ToolCallingModel: llm})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 调用方自定义的 Option 与修改 ToolSession 的内置 Option 一起使用
+	custom := Option(func(a *Agent) ([]agent.AgentOption, error) {
+		return []agent.AgentOption{agent.WithComposeOptions(compose.WithChatModelOption(model.WithTemperature(0.5)))}, nil
+	})
+	updates := 0
+	observe := withMessages(func(msgs []*schema.Message) { updates++ })
+	if _, err = a.Generate(ctx, []*schema.Message{schema.UserMessage("hi")}, custom, observe); err != nil {
+		t.Fatal(err)
+	}
+	if temperature == nil || *temperature != 0.5 || updates == 0 {
+		t.Fatalf("got temperature %v after %d message updates, want 0.5 after some updates", temperature, updates)
+	}
+}
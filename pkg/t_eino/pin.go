@@ -0,0 +1,86 @@
+package t_eino
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// PinnedExtraKey 固定的消息在 Extra 中标记为 true，内置的 rewriter 和压缩都不会丢弃或总结固定的消息，
+// 适用于 system 指令、用户提供的文件、skill 说明等必须完整保留的内容
+const PinnedExtraKey = "_agent_pinned"
+
+// PinMessage 返回标记为固定的消息副本，不修改 msg。
+// assistant 的 tool call 与对应的 tool 结果作为一组保留，其中任意一条固定时整组都会保留。
+func PinMessage(msg *schema.Message) *schema.Message {
+	cp := *msg
+	cp.Extra = make(map[string]any, len(msg.Extra)+1)
+	for k, v := range msg.Extra {
+		cp.Extra[k] = v
+	}
+	cp.Extra[PinnedExtraKey] = true
+	return &cp
+}
+
+// IsPinned 消息是否被固定
+func IsPinned(msg *schema.Message) bool {
+	v, _ := msg.Extra[PinnedExtraKey].(bool)
+	return v
+}
+
+// pinnedMessage 调用 MessageRewriter 前记录的固定消息
+type pinnedMessage struct {
+	index   int
+	role    schema.RoleType
+	key     string
+	summary bool
+}
+
+// pinnedKey 固定消息的内容，Extra 和 ResponseMeta 不参与比较
+func pinnedKey(msg *schema.Message) string {
+	cp := *msg
+	cp.Extra = nil
+	cp.ResponseMeta = nil
+	b, _ := json.Marshal(&cp)
+	return string(b)
+}
+
+func collectPinned(msgs []*schema.Message) []pinnedMessage {
+	var pinned []pinnedMessage
+	for i, msg := range msgs {
+		if IsPinned(msg) {
+			pinned = append(pinned, pinnedMessage{index: i, role: msg.Role, key: pinnedKey(msg), summary: IsSummaryMessage(msg)})
+		}
+	}
+	return pinned
+}
+
+// checkPinned 校验 MessageRewriter 的输出仍包含改写前所有的固定消息且内容未被修改，
+// 摘要消息例外：输出中有新的摘要消息时，旧的摘要可以被替换
+func checkPinned(pinned []pinnedMessage, output []*schema.Message) error {
+	if len(pinned) == 0 {
+		return nil
+	}
+	remain := make(map[string]int, len(pinned))
+	hasSummary := false
+	for _, msg := range output {
+		if IsPinned(msg) {
+			remain[pinnedKey(msg)]++
+		}
+		if IsSummaryMessage(msg) {
+			hasSummary = true
+		}
+	}
+	for _, p := range pinned {
+		if remain[p.key] > 0 {
+			remain[p.key]--
+			continue
+		}
+		if p.summary && hasSummary {
+			continue
+		}
+		return fmt.Errorf("pinned %s message at index %d is removed or modified", p.role, p.index)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package t_eino
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+func TestCheckPinned(t *testing.T) {
+	file := PinMessage(schema.UserMessage("file"))
+	summary := newSummaryMessage("old")
+	input := []*schema.Message{schema.SystemMessage("s"), summary, file, schema.UserMessage("q")}
+	tests := []struct {
+		name    string
+		output  []*schema.Message
+		wantErr bool
+	}{
+		{name: "unchanged", output: input},
+		{name: "unpinned message removed", output: []*schema.Message{summary, file}},
+		// Extra 不参与比较
+		{name: "pinned message copied", output: []*schema.Message{summary, PinMessage(file)}},
+		{name: "summary replaced", output: []*schema.Message{newSummaryMessage("new"), file}},
+		{name: "pinned message removed", output: []*schema.Message{summary, schema.UserMessage("q")}, wantErr: true},
+		{name: "pinned message modified", output: []*schema.Message{summary, PinMessage(schema.UserMessage("changed"))}, wantErr: true},
+		{name: "summary removed", output: []*schema.Message{file}, wantErr: true},
+		{name: "pin removed", output: []*schema.Message{summary, schema.UserMessage("file")}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkPinned(collectPinned(input), tt.output)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got err %v, want err %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPinMessage(t *testing.T) {
+	msg := schema.UserMessage("file")
+	msg.Extra = map[string]any{"k": "v"}
+	pinned := PinMessage(msg)
+	if !IsPinned(pinned) || pinned.Extra["k"] != "v" {
+		t.Fatalf("got extra %v", pinned.Extra)
+	}
+	if IsPinned(msg) {
+		t.Fatal("PinMessage modified the original message")
+	}
+}
+
+func TestSlidingWindowKeepsPinned(t *testing.T) {
+	ctx := context.Background()
+	msgs := []*schema.Message{
+		schema.SystemMessage("s"),
+		PinMessage(schema.UserMessage("file")),
+		schema.AssistantMessage("ok", nil),
+		toolCallMessage(newToolCall("a", "t", "{}")),
+		PinMessage(schema.ToolMessage("ra", "a")),
+		schema.UserMessage("u1"),
+		schema.AssistantMessage("x", nil),
+		schema.UserMessage("u2"),
+	}
+	rw, err := NewSlidingWindowRewriter(&SlidingWindowConfig{MaxTokens: 5, Tokenizer: oneTokenizer})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := rw(ctx, msgs)
+	// 固定的 tool 结果连同它的 tool call 一起保留
+	if got, want := dumpMessages(out), "system:s user:file assistant: tool:ra user:u2"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	if err = checkPinned(collectPinned(msgs), out); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAgentPinnedRemoved(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name     string
+		rewriter MessageModifier
+		input    []*schema.Message
+		wantErr  bool
+	}{
+		{
+			name:     "rewriter drops pinned message",
+			rewriter: func(ctx context.Context, in []*schema.Message) []*schema.Message { return in[len(in)-1:] },
+			input:    []*schema.Message{PinMessage(schema.UserMessage("file")), schema.UserMessage("q")},
+			wantErr:  true,
+		},
+		{
+			name: "rewriter modifies pinned message in place",
+			rewriter: func(ctx context.Context, in []*schema.Message) []*schema.Message {
+				in[0].Content = "changed"
+				return in
+			},
+			input:   []*schema.Message{PinMessage(schema.UserMessage("file")), schema.UserMessage("q")},
+			wantErr: true,
+		},
+		{
+			name:     "rewriter drops unpinned message",
+			rewriter: func(ctx context.Context, in []*schema.Message) []*schema.Message { return in[len(in)-1:] },
+			input:    []*schema.Message{schema.UserMessage("file"), schema.UserMessage("q")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := NewAgent(ctx, &AgentConfig{ToolCallingModel: newReplyModel("done"), MessageRewriter: tt.rewriter})
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = a.Generate(ctx, tt.input)
+			if IsPinnedRemoved(err) != tt.wantErr {
+				t.Fatalf("got %v, want pinned removed %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
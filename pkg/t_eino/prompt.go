@@ -2,5 +2,60 @@ package t_eino
 
 // Tool
 const (
-	GetToolToolDescription = ""
+	GetToolToolDescription     = "获取一个额外的 tool，获取成功后即可直接调用该 tool。参数 name 为 tool 的名称，不知道名称时先调用 " + SpecialSearchToolsToolName + " 搜索。"
+	SearchToolsToolDescription = "根据关键词在尚未获取的额外 tool 中搜索，返回最相关的 tool 名称和描述，之后可调用 " + SpecialGetToolToolName + " 获取需要的 tool。"
+	SearchToolsResultPrompt    = "搜索到以下 tool，调用 " + SpecialGetToolToolName + " 获取后即可使用："
+	SearchToolsEmptyResult     = "没有搜索到相关的 tool，请换个关键词。"
+	ReleaseToolToolDescription = "释放一个通过 " + SpecialGetToolToolName + " 获取的、暂时不再需要的 tool，释放后仍可重新获取。参数 name 为 tool 的名称。"
+)
+
+// Skill
+const (
+	UseSkillToolDescription = "激活一个 skill，激活后会获得该 skill 的使用说明和它包含的 tool。参数 name 为 skill 列表中的名称。"
+	SkillListPrompt         = "以下是可以使用的 skill，需要时请先调用 " + SpecialUseSkillToolName + " 激活："
+	ActiveSkillPrompt       = "已激活的 skill【%s】使用说明："
+)
+
+// Skill 资源
+const (
+	ReadSkillResourceToolDescription = "读取已激活 skill 目录下的资源文件。参数 skill 为 skill 名称，path 为 skill 说明中列出的资源相对路径。"
+	SkillResourcesPrompt             = "该 skill 包含以下资源文件，需要时调用 " + SpecialReadSkillResourceToolName + " 读取："
+)
+
+// 审批
+const (
+	ToolCallRejectedResult = "该 tool 调用未通过人工审批，没有执行"
+)
+
+// 下一步推荐
+const (
+	SuggestQuestionsToolDescription = "给出用户接下来可能想问的问题"
+	FollowUpPrompt                  = `根据下面的对话，推测用户接下来最可能想问的 %d 个问题，并调用 ` + suggestQuestionsToolName + ` 给出。
+要求：问题站在用户的角度提问，与对话内容紧密相关，简短具体，互不重复，使用与用户相同的语言。`
+	FollowUpContextPrompt = "以下是对话内容："
+)
+
+// 压缩
+const (
+	AddSkillToolDescription = "将需要压缩的上下文总结为一个 skill，之后的对话中该段上下文会被替换为这个 skill。" +
+		"参数 name 为 skill 的名称（简短的英文或拼音，使用下划线连接），description 为一行简介，instructions 为完整的总结。"
+	CompressSystemPrompt = `你是上下文压缩者，唯一的工作是调用 ` + AddSkillToolName + ` 将下面这段对话总结为一个 skill，之后的上下文中这段对话会被替换为该 skill。
+总结规则：
+1. instructions 需要保留之后可能用到的所有关键信息：用户的需求和偏好、得到的结论、重要的数据、tool 的调用结果、未完成的事项。
+2. description 用一句话说明这个 skill 包含什么信息，便于之后判断是否需要激活。
+3. 不要输出其它内容，只调用一次 ` + AddSkillToolName + `。`
+	CompressSkillListPrompt = "已有以下由压缩得到的 skill，如果这段对话引用了它们，可以通过 sub_skills 将它们隐藏到新 skill 之下："
+	CompressContextPrompt   = "以下是需要压缩的对话："
+)
+
+// 摘要记忆
+const (
+	SummaryPrompt = `你是对话摘要者，将下面的对话总结为一段摘要，之后的对话中这段对话会被替换为摘要。
+要求：
+1. 保留之后可能用到的所有关键信息：用户的需求和偏好、得到的结论、重要的数据、tool 的调用结果、未完成的事项。
+2. 如果给出了已有的摘要，将其与新的对话合并为一份完整的摘要，不要丢失已有摘要中的信息。
+3. 只输出摘要本身，使用与用户相同的语言。`
+	SummaryPreviousPrompt = "已有的摘要："
+	SummaryContextPrompt  = "以下是需要总结的对话："
+	SummaryMessagePrompt  = "以下是之前对话的摘要：\n"
 )
@@ -3,20 +3,30 @@ package t_eino
 import (
 	"context"
 	"errors"
-	"github.com/cloudwego/eino/flow/agent/react"
+	"fmt"
 	"io"
 	"sync"
 
+	"github.com/cloudwego/eino/callbacks"
+	"github.com/cloudwego/eino/components/embedding"
 	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/tool"
 	"github.com/cloudwego/eino/compose"
 	"github.com/cloudwego/eino/flow/agent"
 	"github.com/cloudwego/eino/schema"
+	"github.com/google/uuid"
 )
 
 type state struct {
 	Messages                 []*schema.Message
 	ReturnDirectlyToolCallID string
-	toolCallIDMap            map[string]string //tool_call_id映射对应的tool_name
+	Step                     int               //ChatModel 已被调用的次数
+	ToolsPreloaded           bool              //是否已按 user message 预先获取过 tool
+	RejectedToolCalls        map[string]string //审批时被拒绝的 tool_call_id 及原因
+	ToolCallIDMap            map[string]string //tool_call_id映射对应的tool_name
+	Session                  *ToolSessionState //保存运行状态时 toolSession 的快照
+	Routes                   []*RouteDecision  //每次调用 ChatModel 的路由结果，配置了 RouteRules 时记录
+	toolSession              *ToolSession      //本次运行独享的 tool 状态
 	lock                     sync.RWMutex
 }
 
@@ -24,6 +34,14 @@ func init() {
 	schema.RegisterName[*state]("_my_eino_react_state")
 }
 
+// getToolSession 从 checkpoint 恢复的 state 中没有 toolSession，需要从 ctx 中重新取回
+func (s *state) getToolSession(ctx context.Context) *ToolSession {
+	if s.toolSession == nil {
+		s.toolSession = toolSessionFromCtx(ctx)
+	}
+	return s.toolSession
+}
+
 const (
 	nodeKeyTools = "tools"
 	nodeKeyModel = "chat"
@@ -41,9 +59,50 @@ type AgentConfig struct {
 	// ToolCallingModel is the chat model to be used for handling user messages with tool calling capability.
 	// This is the recommended model field to use.
 	ToolCallingModel model.ToolCallingChatModel
+	// ModelName 可选，ToolCallingModel 的名称，记录在 ChatModel 输出的 Extra 中（见 GetModelName），默认 primary
+	ModelName string
+	// FallbackModels 可选，ToolCallingModel 遇到超时、429、5xx 或空响应时按顺序使用的备用模型
+	FallbackModels []*NamedModel
+	// RouteModels 可选，只能被 RouteRules 选中的模型，如处理简单 tool 调度的低成本模型
+	RouteModels []*NamedModel
+	// RouteRules 可选，每次调用 ChatModel 前按顺序匹配，选择第一条命中规则的模型，都不命中时使用 ToolCallingModel。
+	// 选中的模型失败时回退到 ToolCallingModel 和 FallbackModels，路由结果见 GetRouteDecisions。
+	RouteRules []*RouteRule
 
 	ToolsConfig compose.ToolsNodeConfig
 
+	// Skills 顶层 skill，大模型只能看到一行简介，通过 special_use_skill 激活后才会注入说明并解锁其 tool
+	Skills []*Skill
+	// DynamicSkills 可选，允许通过 WithSkills 为单次运行注入 skill
+	DynamicSkills bool
+
+	// ExtraTools 可选，每次运行默认注入的额外 tool，等同于每次运行都使用 WithTools 注入
+	ExtraTools []tool.BaseTool
+
+	// ToolEmbedder 可选，设置后会为 WithTools 注入的额外 tool 计算向量，
+	// 并在第一次调用 ChatModel 前按最新的 user message 预先获取最相关的 ToolPreloadTopK 个 tool。
+	ToolEmbedder embedding.Embedder
+	// ToolPreloadTopK 预先获取的 tool 数量，默认 3
+	ToolPreloadTopK int
+	// ToolPreloadMinScore 预先获取的最低余弦相似度，相似度不大于 0 的 tool 总是不会被获取
+	ToolPreloadMinScore float64
+
+	// MaxAliveTools 可选，被大模型“看到”的 tool 的最大数量（包含 config tools）。
+	// 超出时淘汰最久未使用的动态获取的 tool，config tools 和 skill 的 tool 不会被淘汰。
+	MaxAliveTools int
+	// MaxToolTokens 可选，被大模型“看到”的 tool schema 估算 token 总数上限，淘汰规则同 MaxAliveTools。
+	MaxToolTokens int
+
+	// ToolApproval 可选，命中的 tool call 在执行前会暂停运行，Generate 返回 *ApprovalRequiredError，
+	// 人工审批（通过、修改参数或拒绝）后使用 Agent.Resume 继续运行。需要同时设置 CheckPointStore。
+	ToolApproval *ToolApprovalConfig
+	// CheckPointStore 可选，设置后每完成一步都会保存运行状态，运行失败、中断或进程崩溃后可使用 Agent.Resume 继续。
+	// 设置了 ToolApproval 时必须设置。
+	CheckPointStore CheckPointStore
+
+	// FollowUp 可选，得到最终结果后生成推荐的问题，见 FollowUpConfig
+	FollowUp *FollowUpConfig
+
 	// MessageModifier.
 	// modify the input messages before the model is called, it's useful when you want to add some system prompt or other messages.
 	MessageModifier MessageModifier
@@ -53,6 +112,7 @@ type AgentConfig struct {
 	// Useful for compressing message history to fit the model context window,
 	// or if you want to make changes to messages that take effect across multiple model calls.
 	// NOTE: if both MessageModifier and MessageRewriter are set, MessageRewriter will be called before MessageModifier.
+	// Pinned messages (see PinMessage) must be kept unchanged, otherwise the run fails with ErrPinnedRemoved.
 	MessageRewriter MessageModifier
 
 	// MaxStep.
@@ -89,16 +149,12 @@ type AgentConfig struct {
 	ToolsNodeName string
 }
 
-func NormalStop(err error) bool {
-	return errors.As(err, &io.EOF) || errors.As(err, &StopRunErr)
-}
-
 func getToolName(ctx context.Context, toolCallID string) string {
 	var name string
 	compose.ProcessState[*state](ctx, func(ctx context.Context, s *state) error {
 		s.lock.RLock()
 		defer s.lock.RUnlock()
-		name = s.toolCallIDMap[toolCallID]
+		name = s.ToolCallIDMap[toolCallID]
 		return nil
 	})
 	return name
@@ -119,8 +175,6 @@ func checkChunkStreamToolCallChecker(_ context.Context, sr *schema.StreamReader[
 			return true, nil
 		}
 	}
-
-	return false, nil
 }
 
 const (
@@ -155,9 +209,11 @@ func SetReturnDirectly(ctx context.Context) error {
 //	println(msg.Content)
 type Agent struct {
 	toolList         *ToolList
+	checkPointStore  CheckPointStore
 	runnable         compose.Runnable[[]*schema.Message, *schema.Message]
 	graph            *compose.Graph[[]*schema.Message, *schema.Message]
 	graphAddNodeOpts []compose.GraphAddNodeOpt
+	followUp         *followUp
 }
 
 // NewAgent creates a ReAct t_eino that feeds tool response into next round of Chat Model generation.
@@ -174,12 +230,19 @@ func NewAgent(ctx context.Context, config *AgentConfig) (_ *Agent, err error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Agent{
+	a := &Agent{
 		toolList:         t,
+		checkPointStore:  config.CheckPointStore,
 		runnable:         runnable,
 		graph:            graph,
 		graphAddNodeOpts: []compose.GraphAddNodeOpt{compose.WithGraphCompileOptions(opts...)},
-	}, nil
+	}
+	if config.FollowUp != nil {
+		if a.followUp, err = newFollowUp(config.FollowUp, config.ToolCallingModel); err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
 }
 
 func GetReactGraph(ctx context.Context, config *AgentConfig) (graph *compose.Graph[[]*schema.Message, *schema.Message], t *ToolList, opts []compose.GraphCompileOption, err error) {
@@ -209,20 +272,49 @@ func GetReactGraph(ctx context.Context, config *AgentConfig) (graph *compose.Gra
 	if toolCallChecker == nil {
 		toolCallChecker = checkChunkStreamToolCallChecker
 	}
-	chatModel = NewLearnModel(ctx, config.ToolCallingModel)
-	infos := make([]*schema.ToolInfo, 0, len(config.ToolsConfig.Tools))
-	for _, tool := range config.ToolsConfig.Tools {
-		info, err := tool.Info(ctx)
-		if err != nil {
-			return nil, nil, nil, err
-		}
-		infos = append(infos, info)
+	// 审批暂停的运行保存在 CheckPointStore 中，由调用方决定存放位置和清理方式
+	if config.ToolApproval != nil && config.CheckPointStore == nil {
+		return nil, nil, nil, fmt.Errorf("tool approval requires a checkpoint store")
 	}
-	chatModel, err = chatModel.WithTools(infos)
+	t, err = NewToolList(ctx, &ToolListConfig{
+		Tools:           config.ToolsConfig.Tools,
+		Skills:          config.Skills,
+		DynamicSkills:   config.DynamicSkills,
+		ExtraTools:      config.ExtraTools,
+		Embedder:        config.ToolEmbedder,
+		PreloadTopK:     config.ToolPreloadTopK,
+		PreloadMinScore: config.ToolPreloadMinScore,
+		MaxAliveTools:   config.MaxAliveTools,
+		MaxToolTokens:   config.MaxToolTokens,
+	})
 	if err != nil {
 		return nil, nil, nil, err
 	}
-	t, err = NewToolList(ctx, config.ToolsConfig.Tools, chatModel)
+	modelName := config.ModelName
+	if modelName == "" {
+		modelName = defaultModelName
+	}
+	chatModel, err = NewRoutedLearnModel(ctx, append([]*NamedModel{{Name: modelName, Model: config.ToolCallingModel}}, config.FallbackModels...),
+		config.RouteModels, config.RouteRules)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	infos, err := genToolInfos(ctx, compose.ToolsNodeConfig{Tools: t.Tools()})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	toolsConfig = config.ToolsConfig
+	// ToolsNode 默认可执行 config 中的额外 tool 和 skill 的 tool，Agent 运行时再通过 WithToolList 传入本次运行的 tool
+	if toolsConfig.Tools, err = t.NewSession().runTools(ctx); err != nil {
+		return nil, nil, nil, err
+	}
+	toolsConfig.UnknownToolsHandler = unknownToolsHandler(config.ToolsConfig.UnknownToolsHandler)
+	toolsConfig.ToolCallMiddlewares = append([]compose.ToolMiddleware{errorMiddleware(), aliveToolsMiddleware(config.ToolsConfig.UnknownToolsHandler)},
+		config.ToolsConfig.ToolCallMiddlewares...)
+	if config.ToolApproval != nil {
+		toolsConfig.ToolCallMiddlewares = append(toolsConfig.ToolCallMiddlewares, approvalMiddleware())
+	}
+	chatModel, err = chatModel.WithTools(infos)
 	if err != nil {
 		return nil, nil, nil, err
 	}
@@ -232,22 +324,54 @@ func GetReactGraph(ctx context.Context, config *AgentConfig) (graph *compose.Gra
 	}
 
 	graph = compose.NewGraph[[]*schema.Message, *schema.Message](compose.WithGenLocalState(func(ctx context.Context) *state {
-		return &state{Messages: make([]*schema.Message, 0, config.MaxStep+1)}
+		session := toolSessionFromCtx(ctx)
+		if session == nil {
+			// 图被嵌入其它图运行时没有经过 Agent，这里为每次运行单独创建
+			session = t.NewSession()
+		}
+		if info := runInfoFromCtx(ctx); info != nil && info.restored != nil {
+			info.restored.toolSession = session
+			return info.restored
+		}
+		return &state{Messages: make([]*schema.Message, 0, config.MaxStep+1), toolSession: session}
 	}))
 
 	modelPreHandle := func(ctx context.Context, input []*schema.Message, state *state) ([]*schema.Message, error) {
 		state.Messages = append(state.Messages, input...)
+		state.Step++
+		if info := runInfoFromCtx(ctx); info != nil {
+			info.step.Store(int64(state.Step))
+		}
+
+		session := state.getToolSession(ctx)
+		if !state.ToolsPreloaded {
+			state.ToolsPreloaded = true
+			if err := session.PreloadTools(ctx, latestUserQuery(state.Messages)); err != nil {
+				return nil, err
+			}
+		}
 
 		if config.MessageRewriter != nil {
+			pinned := collectPinned(state.Messages)
 			state.Messages = config.MessageRewriter(ctx, state.Messages)
+			if err := checkPinned(pinned, state.Messages); err != nil {
+				return nil, &AgentError{Kind: ErrPinnedRemoved, Step: state.Step, Err: err}
+			}
 		}
 
+		if err := saveRunSnapshot(ctx, state); err != nil {
+			return nil, err
+		}
+		session.notifyMessages(state.Messages)
+
+		msgs := injectSkillPrompt(session, state.Messages)
+
 		if messageModifier == nil {
-			return state.Messages, nil
+			return msgs, nil
 		}
 
-		modifiedInput := make([]*schema.Message, len(state.Messages))
-		copy(modifiedInput, state.Messages)
+		modifiedInput := make([]*schema.Message, len(msgs))
+		copy(modifiedInput, msgs)
 		return messageModifier(ctx, modifiedInput), nil
 	}
 
@@ -255,40 +379,51 @@ func GetReactGraph(ctx context.Context, config *AgentConfig) (graph *compose.Gra
 		return nil, nil, nil, err
 	}
 
-	if err = graph.AddEdge(compose.START, nodeKeyModel); err != nil {
-		return nil, nil, nil, err
-	}
-
 	toolsNodePreHandle := func(ctx context.Context, input *schema.Message, state *state) (*schema.Message, error) {
 		state.lock.Lock()
 		defer state.lock.Unlock()
 		if input == nil {
 			return state.Messages[len(state.Messages)-1], nil // used for rerun interrupt resume
 		}
-		if state.toolCallIDMap == nil {
-			state.toolCallIDMap = make(map[string]string)
+		if state.ToolCallIDMap == nil {
+			state.ToolCallIDMap = make(map[string]string)
 		}
 		for _, toolCall := range input.ToolCalls {
-			state.toolCallIDMap[toolCall.ID] = toolCall.Function.Name
+			state.ToolCallIDMap[toolCall.ID] = toolCall.Function.Name
 		}
 		state.Messages = append(state.Messages, input)
 		state.ReturnDirectlyToolCallID = getReturnDirectlyToolCallID(input, config.ToolReturnDirectly)
+		if err := saveRunSnapshot(ctx, state); err != nil {
+			return nil, err
+		}
+		state.getToolSession(ctx).notifyMessages(state.Messages)
 		return input, nil
 	}
 	if err = graph.AddToolsNode(nodeKeyTools, toolsNode, compose.WithStatePreHandler(toolsNodePreHandle), compose.WithNodeName(toolsNodeName)); err != nil {
 		return nil, nil, nil, err
 	}
 
+	toolCallTarget := nodeKeyTools
+	if config.ToolApproval != nil {
+		if toolCallTarget, err = buildApproval(graph, config.ToolApproval); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	if err = buildResume(graph, toolCallTarget); err != nil {
+		return nil, nil, nil, err
+	}
+
 	modelPostBranchCondition := func(ctx context.Context, sr *schema.StreamReader[*schema.Message]) (endNode string, err error) {
 		if isToolCall, err := toolCallChecker(ctx, sr); err != nil {
 			return "", err
 		} else if isToolCall {
-			return nodeKeyTools, nil
+			return toolCallTarget, nil
 		}
 		return compose.END, nil
 	}
 
-	if err = graph.AddBranch(nodeKeyModel, compose.NewStreamGraphBranch(modelPostBranchCondition, map[string]bool{nodeKeyTools: true, compose.END: true})); err != nil {
+	if err = graph.AddBranch(nodeKeyModel, compose.NewStreamGraphBranch(modelPostBranchCondition, map[string]bool{toolCallTarget: true, compose.END: true})); err != nil {
 		return nil, nil, nil, err
 	}
 
@@ -297,6 +432,9 @@ func GetReactGraph(ctx context.Context, config *AgentConfig) (graph *compose.Gra
 	}
 
 	opts = []compose.GraphCompileOption{compose.WithMaxRunSteps(config.MaxStep), compose.WithNodeTriggerMode(compose.AnyPredecessor), compose.WithGraphName(graphName)}
+	if config.CheckPointStore != nil {
+		opts = append(opts, compose.WithCheckPointStore(config.CheckPointStore))
+	}
 	return
 }
 
@@ -382,27 +520,204 @@ func getReturnDirectlyToolCallID(input *schema.Message, toolReturnDirectly map[s
 
 // Generate generates a response from the t_eino.
 func (r *Agent) Generate(ctx context.Context, input []*schema.Message, opts ...Option) (*schema.Message, error) {
-	r.toolList.Init()
-	option, err := r.getAgentOption(opts...)
+	session := r.toolList.NewSession()
+	option, err := r.getAgentOption(session, opts...)
 	if err != nil {
 		return nil, err
 	}
-	return r.runnable.Invoke(ctx, input, agent.GetComposeOptions(option...)...)
+	ctx = withToolSession(ctx, session)
+	var composeOpts []compose.Option
+	if r.checkPointStore != nil {
+		// 同一 run ID 可能残留上次运行中断时的 checkpoint，这里总是从头运行
+		composeOpts = append(composeOpts, compose.WithForceNewRun())
+	}
+	return r.invokeRun(ctx, &runInfo{id: session.runID, store: r.checkPointStore}, input, option, composeOpts...)
 }
 
-// Stream calls the t_eino and returns a stream response.
-func (r *Agent) Stream(ctx context.Context, input []*schema.Message, options ...Option) (output *react.Iterator[*schema.StreamReader[*schema.Message]], err error) {
-	//return r.runnable.Stream(ctx, input, agent.GetComposeOptions(opts...)...)
-	r.toolList.Init()
-	opts, err := r.getAgentOption(options...)
+// Resume 继续 runID 对应的运行：
+//   - 因人工审批暂停的运行按 decisions 继续，decisions 的 key 为 tool_call_id，没有审批结果的待审批 tool call 视为拒绝；
+//   - 失败或进程崩溃的运行从最近完成的一步继续，decisions 被忽略。
+//
+// 成功结束的运行不再保存状态，返回不存在的错误。
+//
+// opts 需要与原运行保持一致，如 WithTools 注入的额外 tool。
+func (r *Agent) Resume(ctx context.Context, runID string, decisions map[string]*ApprovalDecision, opts ...Option) (*schema.Message, error) {
+	if r.checkPointStore == nil {
+		return nil, fmt.Errorf("agent has no checkpoint store")
+	}
+	snapshot, ok, err := getRunSnapshot(ctx, r.checkPointStore, runID)
 	if err != nil {
 		return nil, err
 	}
-	opt, mf := react.WithMessageFuture()
-	opts = append(opts, opt)
-	go r.runnable.Stream(ctx, input, agent.GetComposeOptions(opts...)...)
-	output = mf.GetMessageStreams()
-	return
+	if !ok {
+		return nil, fmt.Errorf("run %s is not exist", runID)
+	}
+
+	session := r.toolList.NewSession()
+	option, err := r.getAgentOption(session, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if snapshot.State != nil {
+		if err = session.Restore(ctx, snapshot.State.Session); err != nil {
+			return nil, err
+		}
+	}
+	ctx = withToolSession(ctx, session)
+	info := &runInfo{id: runID, store: r.checkPointStore}
+	if snapshot.Interrupted {
+		if decisions == nil {
+			decisions = make(map[string]*ApprovalDecision)
+		}
+		ctx = withApprovalDecisions(ctx, decisions)
+		return r.invokeRun(ctx, info, nil, option)
+	}
+	if snapshot.State == nil {
+		return nil, fmt.Errorf("run %s has no completed step", runID)
+	}
+	info.restored = snapshot.State
+	return r.invokeRun(ctx, info, nil, option, compose.WithForceNewRun())
+}
+
+// invokeRun 执行图并将错误转换为 AgentError，设置了 CheckPointStore 时在中断时更新运行快照，成功结束时删除运行状态
+func (r *Agent) invokeRun(ctx context.Context, info *runInfo, input []*schema.Message, option []agent.AgentOption, opts ...compose.Option) (*schema.Message, error) {
+	ctx, composeOpts, err := r.prepareRun(ctx, info, option, opts...)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := r.runnable.Invoke(ctx, input, composeOpts...)
+	if msg, err = r.finishRun(ctx, info, msg, err); err != nil {
+		return msg, err
+	}
+	return withFollowUpQuestions(msg, r.followUpQuestions(ctx, info, msg)), nil
+}
+
+func (r *Agent) prepareRun(ctx context.Context, info *runInfo, option []agent.AgentOption, opts ...compose.Option) (context.Context, []compose.Option, error) {
+	if info.id == "" {
+		info.id = uuid.NewString()
+	}
+	ctx = withRunInfo(ctx, info)
+	composeOpts := agent.GetComposeOptions(option...)
+	if session := toolSessionFromCtx(ctx); session != nil {
+		if r.followUp != nil {
+			session.onMessages = append(session.onMessages, func(msgs []*schema.Message) {
+				info.messages = append(info.messages[:0], msgs...)
+			})
+		}
+		tools, err := session.runTools(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		composeOpts = append(composeOpts, compose.WithToolsNodeOption(compose.WithToolList(tools...)).DesignateNode(nodeKeyTools))
+	}
+	if info.store != nil {
+		composeOpts = append(composeOpts, compose.WithCheckPointID(info.id))
+	}
+	return ctx, append(composeOpts, opts...), nil
+}
+
+func (r *Agent) finishRun(ctx context.Context, info *runInfo, msg *schema.Message, err error) (*schema.Message, error) {
+	if info.store == nil {
+		return msg, runError(int(info.step.Load()), err)
+	}
+	if err != nil {
+		err = runError(int(info.step.Load()), err)
+		if _, ok := compose.ExtractInterruptInfo(err); ok {
+			if serr := r.markInterrupted(ctx, info.id); serr != nil {
+				return nil, &RunError{RunID: info.id, Err: errors.Join(err, serr)}
+			}
+		}
+		if ae, ok := approvalError(info.id, err); ok {
+			return nil, ae
+		}
+		return nil, &RunError{RunID: info.id, Err: err}
+	}
+	if err = deleteRun(ctx, r.checkPointStore, info.id); err != nil {
+		return nil, &RunError{RunID: info.id, Err: err}
+	}
+	return msg, nil
+}
+
+// markInterrupted 中断前的 state 已由 compose 保存，这里保留最近一步的快照用于恢复 ToolSession
+func (r *Agent) markInterrupted(ctx context.Context, runID string) error {
+	snapshot, ok, err := getRunSnapshot(ctx, r.checkPointStore, runID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		snapshot = &runSnapshot{}
+	}
+	snapshot.Interrupted = true
+	return setRunSnapshot(ctx, r.checkPointStore, runID, snapshot)
+}
+
+// Stream 流式运行，返回的 StreamIterator 依次输出每次 ChatModel 的输出流和每个 tool 的结果，
+// 最后一项携带最终结果和运行错误。不再读取时需调用 StreamIterator.Close，取消 ctx 同样会终止运行。
+func (r *Agent) Stream(ctx context.Context, input []*schema.Message, options ...Option) (*StreamIterator, error) {
+	q := newRunQueue(discardStreamChunk)
+	err := r.startStream(ctx, input, options, streamChunkHandler(q), func(_ string, cancel context.CancelFunc) {
+		q.cancel = cancel
+	}, func(_ string, msg *schema.Message, err error, followUp func() []string) {
+		if err == nil {
+			// 最后一项的 Output 需要携带推荐问题，因此在生成推荐问题后输出
+			msg = withFollowUpQuestions(msg, followUp())
+		}
+		q.finish(&StreamChunk{Done: true, Output: msg, Err: err})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &StreamIterator{q: q}, nil
+}
+
+// startStream 在后台流式运行，handler 通过 callbacks 收集运行过程，start 在运行开始前调用，finish 在运行结束后调用，
+// 运行成功时可在 finish 中调用 followUp 生成推荐问题，由调用方决定推荐问题与最终结果的输出顺序
+func (r *Agent) startStream(ctx context.Context, input []*schema.Message, options []Option, handler callbacks.Handler,
+	start func(runID string, cancel context.CancelFunc), finish func(runID string, msg *schema.Message, err error, followUp func() []string)) error {
+	session := r.toolList.NewSession()
+	opts, err := r.getAgentOption(session, options...)
+	if err != nil {
+		return err
+	}
+	ctx = withToolSession(ctx, session)
+	ctx, cancel := context.WithCancel(ctx)
+	opts = append(opts, agent.WithComposeOptions(compose.WithCallbacks(handler)))
+	var composeOpts []compose.Option
+	if r.checkPointStore != nil {
+		composeOpts = append(composeOpts, compose.WithForceNewRun())
+	}
+	info := &runInfo{id: session.runID, store: r.checkPointStore}
+	if ctx, composeOpts, err = r.prepareRun(ctx, info, opts, composeOpts...); err != nil {
+		cancel()
+		return err
+	}
+	start(info.id, cancel)
+	go func() {
+		var (
+			msg *schema.Message
+			err error
+		)
+		defer func() {
+			if p := recover(); p != nil {
+				err = fmt.Errorf("panic in stream run: %v", p)
+			}
+			msg, err = r.finishRun(ctx, info, msg, err)
+			finish(info.id, msg, err, func() []string {
+				return r.followUpQuestions(ctx, info, msg)
+			})
+		}()
+		sr, err := r.runnable.Stream(ctx, input, composeOpts...)
+		if err != nil {
+			return
+		}
+		msg, err = schema.ConcatMessageStream(sr)
+	}()
+	return nil
+}
+
+// Tools 返回 config tools（包含 special tools），WithTools 注入的额外 tool 不在其中
+func (r *Agent) Tools() []tool.BaseTool {
+	return r.toolList.Tools()
 }
 
 // ExportGraph exports the underlying graph from Agent, along with the []compose.GraphAddNodeOpt to be used when adding this graph to another graph.
@@ -410,8 +725,9 @@ func (r *Agent) ExportGraph() (compose.AnyGraph, []compose.GraphAddNodeOpt) {
 	return r.graph, r.graphAddNodeOpts
 }
 
-func (a *Agent) getAgentOption(options ...Option) ([]agent.AgentOption, error) {
-	agentOpts := make([]agent.AgentOption, len(options))
+// getAgentOption 返回 options 生成的 AgentOption，其中修改 ToolSession 的部分作用于 session
+func (a *Agent) getAgentOption(session *ToolSession, options ...Option) ([]agent.AgentOption, error) {
+	agentOpts := make([]agent.AgentOption, 0, len(options))
 	for _, o := range options {
 		ao, err := o(a)
 		if err != nil {
@@ -419,5 +735,10 @@ func (a *Agent) getAgentOption(options ...Option) ([]agent.AgentOption, error) {
 		}
 		agentOpts = append(agentOpts, ao...)
 	}
+	for _, fn := range agent.GetImplSpecificOptions(&sessionOptions{}, agentOpts...).fns {
+		if err := fn(session); err != nil {
+			return nil, err
+		}
+	}
 	return agentOpts, nil
 }
@@ -0,0 +1,209 @@
+package t_eino
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/tool"
+	"gopkg.in/yaml.v3"
+)
+
+// ToolFactory 按 config 创建 tool，config 来自定义文件，可使用 DecodeConfig 解析为具体的结构体
+type ToolFactory func(ctx context.Context, config map[string]any) (tool.BaseTool, error)
+
+// RewriterFactory 按定义中的 config 创建 MessageRewriter，config 使用 DecodeConfig 解析为具体的结构体。
+// registry 为解析定义的 Registry，需要创建 ChatModel 时使用 registry.ChatModel，使其中注册的 provider 生效
+type RewriterFactory func(ctx context.Context, registry *Registry, config map[string]any) (MessageModifier, error)
+
+// Registry 按名称解析 AgentDefinition 和 WithToolNames 中引用的 tool、rewriter 和 model provider。
+// 可以使用全局的 DefaultRegistry，也可以通过 NewRegistry / Scope 创建独立的作用域。
+type Registry struct {
+	parent    *Registry
+	lock      sync.RWMutex
+	tools     map[string]ToolFactory
+	rewriters map[string]RewriterFactory
+	models    map[string]ModelProvider
+}
+
+// ToolSpec 引用 Registry 中的 tool，定义文件中可以只写名称，也可以写 {name, config}
+type ToolSpec struct {
+	Name   string         `yaml:"name" json:"name"`
+	Config map[string]any `yaml:"config" json:"config"`
+}
+
+// DefaultRegistry 全局 Registry，tool 包可在 init 中注册
+var DefaultRegistry = NewRegistry()
+
+func NewRegistry() *Registry {
+	return &Registry{
+		tools:     make(map[string]ToolFactory),
+		rewriters: make(map[string]RewriterFactory),
+		models:    make(map[string]ModelProvider),
+	}
+}
+
+// Scope 创建子 Registry，查找时先查自身再查父级，注册只影响自身，可以覆盖父级的同名项
+func (r *Registry) Scope() *Registry {
+	s := NewRegistry()
+	s.parent = r
+	return s
+}
+
+// RegisterToolFactory 注册 tool 工厂，同一作用域内重名时返回错误
+func (r *Registry) RegisterToolFactory(name string, factory ToolFactory) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if _, ok := r.tools[name]; ok {
+		return fmt.Errorf("tool %s is already registered", name)
+	}
+	r.tools[name] = factory
+	return nil
+}
+
+// RegisterTypedToolFactory 注册使用类型化 config 的 tool 工厂，config 按 json tag 解析为 C
+func RegisterTypedToolFactory[C any](r *Registry, name string, factory func(ctx context.Context, config *C) (tool.BaseTool, error)) error {
+	return r.RegisterToolFactory(name, func(ctx context.Context, config map[string]any) (tool.BaseTool, error) {
+		c, err := DecodeConfig[C](config)
+		if err != nil {
+			return nil, fmt.Errorf("tool %s: %w", name, err)
+		}
+		return factory(ctx, c)
+	})
+}
+
+// RegisterTools 按 tool 的名称注册已创建的 tool，引用时的 config 会被忽略
+func (r *Registry) RegisterTools(ctx context.Context, tools ...tool.BaseTool) error {
+	for _, tl := range tools {
+		info, err := tl.Info(ctx)
+		if err != nil {
+			return err
+		}
+		if err = r.RegisterToolFactory(info.Name, func(context.Context, map[string]any) (tool.BaseTool, error) {
+			return tl, nil
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Registry) RegisterRewriter(name string, factory RewriterFactory) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if _, ok := r.rewriters[name]; ok {
+		return fmt.Errorf("rewriter %s is already registered", name)
+	}
+	r.rewriters[name] = factory
+	return nil
+}
+
+// RegisterModelProvider 注册只在该 Registry 中生效的 provider，可以覆盖内置的 provider
+func (r *Registry) RegisterModelProvider(name string, provider ModelProvider) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if _, ok := r.models[name]; ok {
+		return fmt.Errorf("model provider %s is already registered", name)
+	}
+	r.models[name] = provider
+	return nil
+}
+
+func (r *Registry) toolFactory(name string) (ToolFactory, bool) {
+	for s := r; s != nil; s = s.parent {
+		s.lock.RLock()
+		factory, ok := s.tools[name]
+		s.lock.RUnlock()
+		if ok {
+			return factory, true
+		}
+	}
+	return nil, false
+}
+
+func (r *Registry) rewriterFactory(name string) (RewriterFactory, bool) {
+	for s := r; s != nil; s = s.parent {
+		s.lock.RLock()
+		factory, ok := s.rewriters[name]
+		s.lock.RUnlock()
+		if ok {
+			return factory, true
+		}
+	}
+	return nil, false
+}
+
+// ChatModel 按 Provider 创建 ChatModel，先查找 Registry 中注册的，再查找全局的
+func (r *Registry) ChatModel(ctx context.Context, config *ModelConfig) (model.ToolCallingChatModel, error) {
+	for s := r; s != nil; s = s.parent {
+		s.lock.RLock()
+		provider, ok := s.models[config.Provider]
+		s.lock.RUnlock()
+		if ok {
+			return provider(ctx, config)
+		}
+	}
+	return NewChatModel(ctx, config)
+}
+
+// Tools 按 ToolSpec 创建 tool，任一不存在或创建失败时返回错误
+func (r *Registry) Tools(ctx context.Context, specs ...ToolSpec) ([]tool.BaseTool, error) {
+	tools := make([]tool.BaseTool, 0, len(specs))
+	for _, spec := range specs {
+		factory, ok := r.toolFactory(spec.Name)
+		if !ok {
+			return nil, fmt.Errorf("tool %s is not registered", spec.Name)
+		}
+		tl, err := factory(ctx, spec.Config)
+		if err != nil {
+			return nil, fmt.Errorf("create tool %s fail: %w", spec.Name, err)
+		}
+		tools = append(tools, tl)
+	}
+	return tools, nil
+}
+
+// ToolsByName 按名称创建 tool，不带 config，可用于构建 AgentConfig.ToolsConfig.Tools
+func (r *Registry) ToolsByName(ctx context.Context, names ...string) ([]tool.BaseTool, error) {
+	specs := make([]ToolSpec, 0, len(names))
+	for _, name := range names {
+		specs = append(specs, ToolSpec{Name: name})
+	}
+	return r.Tools(ctx, specs...)
+}
+
+// Rewriter 按名称创建 MessageRewriter，工厂中创建的 ChatModel 按该 Registry 查找 provider
+func (r *Registry) Rewriter(ctx context.Context, name string, config map[string]any) (MessageModifier, error) {
+	factory, ok := r.rewriterFactory(name)
+	if !ok {
+		return nil, fmt.Errorf("rewriter %s is not registered", name)
+	}
+	return factory(ctx, r, config)
+}
+
+// UnmarshalYAML 支持只写名称的简写
+func (s *ToolSpec) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		return value.Decode(&s.Name)
+	}
+	type plain ToolSpec
+	return value.Decode((*plain)(s))
+}
+
+// DecodeConfig 将定义中的 config 解析为 T，字段使用 json tag
+func DecodeConfig[T any](config map[string]any) (*T, error) {
+	c := new(T)
+	if len(config) == 0 {
+		return c, nil
+	}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("decode config fail: %w", err)
+	}
+	return c, nil
+}
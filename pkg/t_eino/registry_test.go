@@ -0,0 +1,55 @@
+package t_eino
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+func TestRegistryRewriterProvider(t *testing.T) {
+	ctx := context.Background()
+	scope := DefaultRegistry.Scope()
+	err := scope.RegisterModelProvider("scoped", func(ctx context.Context, config *ModelConfig) (model.ToolCallingChatModel, error) {
+		return &scriptedModel{reply: func([]*schema.Message, []*schema.ToolInfo) *schema.Message {
+			return schema.AssistantMessage("summary by "+config.Model, nil)
+		}}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		registry *Registry
+		provider string
+		wantErr  bool
+	}{
+		{name: "scoped provider", registry: scope, provider: "scoped"},
+		{name: "provider not in scope", registry: DefaultRegistry, provider: "scoped", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rewriter, err := tt.registry.Rewriter(ctx, RewriterSummary, map[string]any{
+				"model":          map[string]any{"provider": tt.provider, "model": "m1"},
+				"trigger_tokens": 10,
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got err %v, want err %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			var msgs []*schema.Message
+			for i := 0; i < 4; i++ {
+				msgs = append(msgs, schema.UserMessage(strings.Repeat("question ", 10)), schema.AssistantMessage(strings.Repeat("answer ", 10), nil))
+			}
+			out := rewriter(ctx, msgs)
+			if len(out) == 0 || !IsSummaryMessage(out[0]) || !strings.HasSuffix(out[0].Content, "summary by m1") {
+				t.Fatalf("rewriter did not use the scoped provider, first message: %+v", out[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,225 @@
+package t_eino
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+const (
+	RewriterSlidingWindow = "sliding_window"
+
+	// messageTokenOverhead 每条消息的角色、分隔符等额外开销
+	messageTokenOverhead = 4
+	// defaultReserveTokens 按模型上下文大小计算预算时，默认为输出、MessageModifier 添加的 system prompt 和 tool schema 预留的 token 数
+	defaultReserveTokens = 8192
+)
+
+// Tokenizer 计算消息的 token 数，可替换为模型对应的分词器
+type Tokenizer interface {
+	CountTokens(ctx context.Context, msg *schema.Message) int
+}
+
+// TokenizerFunc 将函数适配为 Tokenizer
+type TokenizerFunc func(ctx context.Context, msg *schema.Message) int
+
+func (f TokenizerFunc) CountTokens(ctx context.Context, msg *schema.Message) int {
+	return f(ctx, msg)
+}
+
+// EstimateTokenizer 默认的 Tokenizer，粗略估算：ASCII 约 4 个字符一个 token，其它字符一个字一个 token
+var EstimateTokenizer Tokenizer = TokenizerFunc(func(_ context.Context, msg *schema.Message) int {
+	return messageTokens(msg) + messageTokenOverhead
+})
+
+var (
+	modelContextSizesLock sync.RWMutex
+	// modelContextSizes 模型名称（或名称前缀）对应的上下文大小
+	modelContextSizes = map[string]int{
+		"gpt-4o":           128000,
+		"gpt-4.1":          1047576,
+		"o3":               200000,
+		"o4-mini":          200000,
+		"deepseek-chat":    65536,
+		"deepseek-v3":      131072,
+		"deepseek-r1":      131072,
+		"doubao-seed-1-6":  262144,
+		"doubao-1-5-pro":   131072,
+		"doubao-1.5-pro":   131072,
+		"qwen-max":         32768,
+		"qwen-plus":        131072,
+		"qwen3":            131072,
+		"llama3.1":         131072,
+		"claude-sonnet-4":  200000,
+		"claude-opus-4":    200000,
+		"gemini-2.5-pro":   1048576,
+		"gemini-2.5-flash": 1048576,
+	}
+)
+
+// RegisterModelContextSize 登记模型的上下文大小，name 可以是完整的模型名称，也可以是名称前缀，已存在时覆盖
+func RegisterModelContextSize(name string, size int) {
+	modelContextSizesLock.Lock()
+	defer modelContextSizesLock.Unlock()
+	modelContextSizes[name] = size
+}
+
+// ModelContextSize 获取模型的上下文大小，先完整匹配，再按最长的前缀匹配
+func ModelContextSize(model string) (int, bool) {
+	modelContextSizesLock.RLock()
+	defer modelContextSizesLock.RUnlock()
+	if size, ok := modelContextSizes[model]; ok {
+		return size, true
+	}
+	names := make([]string, 0, len(modelContextSizes))
+	for name := range modelContextSizes {
+		if strings.HasPrefix(model, name) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return 0, false
+	}
+	sort.Slice(names, func(i, j int) bool { return len(names[i]) > len(names[j]) })
+	return modelContextSizes[names[0]], true
+}
+
+// SlidingWindowConfig token 预算滑动窗口的配置，MaxTokens 和 Model 至少设置一个
+type SlidingWindowConfig struct {
+	// MaxTokens 保留的消息 token 总数上限（包含 system message 和固定的消息）
+	MaxTokens int `json:"max_tokens"`
+	// Model MaxTokens 为 0 时，按 ModelContextSize(Model) - ReserveTokens 计算预算
+	Model string `json:"model"`
+	// ReserveTokens 按模型计算预算时预留的 token 数，默认 8192
+	ReserveTokens int `json:"reserve_tokens"`
+	// Tokenizer 可选，默认 EstimateTokenizer
+	Tokenizer Tokenizer `json:"-"`
+}
+
+// NewSlidingWindowRewriter 按 token 预算从最新的消息往前保留，超出预算的旧消息被丢弃：
+//   - system message 和固定的消息（PinMessage）总是保留，并计入预算；
+//   - assistant 的 tool call 和对应的 tool 结果作为一个整体保留或丢弃；
+//   - 保留的消息是连续的一段，最新的一组消息即使超出预算也会保留。
+func NewSlidingWindowRewriter(config *SlidingWindowConfig) (MessageModifier, error) {
+	budget, err := config.budget()
+	if err != nil {
+		return nil, err
+	}
+	tokenizer := config.Tokenizer
+	if tokenizer == nil {
+		tokenizer = EstimateTokenizer
+	}
+	return func(ctx context.Context, input []*schema.Message) []*schema.Message {
+		return slidingWindow(ctx, input, budget, tokenizer)
+	}, nil
+}
+
+func (c *SlidingWindowConfig) budget() (int, error) {
+	if c.MaxTokens > 0 {
+		return c.MaxTokens, nil
+	}
+	if c.Model == "" {
+		return 0, fmt.Errorf("max_tokens or model is required")
+	}
+	size, ok := ModelContextSize(c.Model)
+	if !ok {
+		return 0, fmt.Errorf("context size of model %s is unknown, set max_tokens or use RegisterModelContextSize", c.Model)
+	}
+	reserve := c.ReserveTokens
+	if reserve <= 0 {
+		reserve = defaultReserveTokens
+	}
+	if size <= reserve {
+		return 0, fmt.Errorf("reserve_tokens %d exceeds the context size %d of model %s", reserve, size, c.Model)
+	}
+	return size - reserve, nil
+}
+
+// messageGroup 必须一起保留或丢弃的消息，start、end 为在原消息中的区间 [start, end)，
+// pinned 为 system message 或组内有固定的消息，总是保留
+type messageGroup struct {
+	start, end int
+	pinned     bool
+}
+
+// groupMessages 将 assistant 的 tool call 与其后对应的 tool 结果分为一组，其它消息各自一组
+func groupMessages(msgs []*schema.Message) []messageGroup {
+	groups := make([]messageGroup, 0, len(msgs))
+	for i := 0; i < len(msgs); {
+		msg := msgs[i]
+		end := i + 1
+		if msg.Role == schema.Assistant && len(msg.ToolCalls) > 0 {
+			ids := make(map[string]struct{}, len(msg.ToolCalls))
+			for _, tc := range msg.ToolCalls {
+				ids[tc.ID] = struct{}{}
+			}
+			for end < len(msgs) && msgs[end].Role == schema.Tool {
+				if _, ok := ids[msgs[end].ToolCallID]; !ok {
+					break
+				}
+				end++
+			}
+		}
+		g := messageGroup{start: i, end: end, pinned: msg.Role == schema.System}
+		for _, m := range msgs[i:end] {
+			g.pinned = g.pinned || IsPinned(m)
+		}
+		groups = append(groups, g)
+		i = end
+	}
+	return groups
+}
+
+func slidingWindow(ctx context.Context, msgs []*schema.Message, budget int, tokenizer Tokenizer) []*schema.Message {
+	groups := groupMessages(msgs)
+	tokens := func(g messageGroup) int {
+		var n int
+		for _, msg := range msgs[g.start:g.end] {
+			n += tokenizer.CountTokens(ctx, msg)
+		}
+		return n
+	}
+	used := 0
+	for _, g := range groups {
+		if g.pinned {
+			used += tokens(g)
+		}
+	}
+	// 从最新的一组往前保留，遇到第一组放不下的即停止，保证保留的是连续的一段
+	first := len(groups)
+	for i := len(groups) - 1; i >= 0; i-- {
+		if groups[i].pinned {
+			continue
+		}
+		n := tokens(groups[i])
+		if used+n > budget && first < len(groups) {
+			break
+		}
+		used += n
+		first = i
+	}
+	if first == 0 {
+		return msgs
+	}
+	result := make([]*schema.Message, 0, len(msgs))
+	for i, g := range groups {
+		if g.pinned || i >= first {
+			result = append(result, msgs[g.start:g.end]...)
+		}
+	}
+	return result
+}
+
+func init() {
+	_ = DefaultRegistry.RegisterRewriter(RewriterSlidingWindow, func(_ context.Context, _ *Registry, config map[string]any) (MessageModifier, error) {
+		c, err := DecodeConfig[SlidingWindowConfig](config)
+		if err != nil {
+			return nil, fmt.Errorf("rewriter %s: %w", RewriterSlidingWindow, err)
+		}
+		return NewSlidingWindowRewriter(c)
+	})
+}
@@ -0,0 +1,70 @@
+package t_eino
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+func TestSlidingWindowRewriter(t *testing.T) {
+	ctx := context.Background()
+	msgs := []*schema.Message{
+		schema.SystemMessage("s"),
+		schema.UserMessage("u1"),
+		toolCallMessage(newToolCall("a", "t", "{}"), newToolCall("b", "t", "{}")),
+		schema.ToolMessage("ra", "a"),
+		schema.ToolMessage("rb", "b"),
+		schema.AssistantMessage("x", nil),
+		schema.UserMessage("u2"),
+	}
+	tests := []struct {
+		name   string
+		budget int
+		want   string
+	}{
+		// 最新的一组即使超出预算也会保留
+		{name: "latest group over budget", budget: 1, want: "system:s user:u2"},
+		{name: "tool call group dropped together", budget: 5, want: "system:s assistant:x user:u2"},
+		{name: "tool call group kept together", budget: 6, want: "system:s assistant: tool:ra tool:rb assistant:x user:u2"},
+		{name: "within budget", budget: 7, want: "system:s user:u1 assistant: tool:ra tool:rb assistant:x user:u2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rw, err := NewSlidingWindowRewriter(&SlidingWindowConfig{MaxTokens: tt.budget, Tokenizer: oneTokenizer})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := dumpMessages(rw(ctx, msgs)); got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSlidingWindowBudget(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  SlidingWindowConfig
+		want    int
+		wantErr bool
+	}{
+		{name: "max tokens", config: SlidingWindowConfig{MaxTokens: 100, Model: "gpt-4o"}, want: 100},
+		{name: "model", config: SlidingWindowConfig{Model: "gpt-4o"}, want: 128000 - defaultReserveTokens},
+		{name: "model prefix", config: SlidingWindowConfig{Model: "deepseek-chat-0324", ReserveTokens: 1000}, want: 65536 - 1000},
+		{name: "unknown model", config: SlidingWindowConfig{Model: "unknown"}, wantErr: true},
+		{name: "reserve exceeds context", config: SlidingWindowConfig{Model: "qwen-max", ReserveTokens: 40000}, wantErr: true},
+		{name: "empty", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.config.budget()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got err %v, want err %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("got budget %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
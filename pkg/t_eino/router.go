@@ -0,0 +1,210 @@
+package t_eino
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+)
+
+// RouteRule 按本次 ChatModel 调用的输入选择模型，规则按顺序匹配，第一条命中的生效，都不命中时使用主模型。
+// 零值的条件不参与判断，所有设置的条件都满足才算命中。
+type RouteRule struct {
+	Name  string `yaml:"name" json:"name"`   //记录在路由轨迹中，默认为 Model
+	Model string `yaml:"model" json:"model"` //目标模型的名称，可以是主模型、备用模型或路由模型
+
+	MinStep         int      `yaml:"min_step" json:"min_step"` //step 从 1 开始
+	MaxStep         int      `yaml:"max_step" json:"max_step"`
+	AfterToolResult *bool    `yaml:"after_tool_result" json:"after_tool_result"` //最后一条消息是否为 tool 的结果
+	MinTokens       int      `yaml:"min_tokens" json:"min_tokens"`               //输入消息的估算 token 数
+	MaxTokens       int      `yaml:"max_tokens" json:"max_tokens"`
+	HasImage        *bool    `yaml:"has_image" json:"has_image"`
+	Skills          []string `yaml:"skills" json:"skills"` //这些 skill 都已激活
+
+	// Match 可选，自定义条件，只能在代码中设置
+	Match func(ctx context.Context, input *RouteInput) bool `yaml:"-" json:"-"`
+}
+
+// RouteInput 路由规则判断的依据
+type RouteInput struct {
+	Step            int
+	Messages        []*schema.Message //本次调用 ChatModel 的输入，已经过 MessageRewriter 和 MessageModifier
+	AfterToolResult bool
+	Tokens          int
+	HasImage        bool
+	ActiveSkills    []string
+}
+
+// RouteDecision 一次路由的结果，按 step 记录在运行状态中，可通过 GetRouteDecisions 或 step_finished 事件获取
+type RouteDecision struct {
+	Step  int
+	Rule  string //命中的规则，未命中时为空
+	Model string //路由选择的模型，出错回退时实际回答的模型见 GetModelName
+}
+
+func (r *RouteRule) name() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return r.Model
+}
+
+func (r *RouteRule) match(ctx context.Context, in *RouteInput) bool {
+	if r.MinStep > 0 && in.Step < r.MinStep {
+		return false
+	}
+	if r.MaxStep > 0 && in.Step > r.MaxStep {
+		return false
+	}
+	if r.AfterToolResult != nil && *r.AfterToolResult != in.AfterToolResult {
+		return false
+	}
+	if r.MinTokens > 0 && in.Tokens < r.MinTokens {
+		return false
+	}
+	if r.MaxTokens > 0 && in.Tokens > r.MaxTokens {
+		return false
+	}
+	if r.HasImage != nil && *r.HasImage != in.HasImage {
+		return false
+	}
+	for _, skill := range r.Skills {
+		if !slices.Contains(in.ActiveSkills, skill) {
+			return false
+		}
+	}
+	return r.Match == nil || r.Match(ctx, in)
+}
+
+// NewRoutedLearnModel 在 NewFallbackLearnModel 的基础上按 rules 为每次调用选择模型，
+// routeModels 只能被路由选中，不参与回退。选中的模型失败时依次回退到 models 中的其它模型。
+func NewRoutedLearnModel(ctx context.Context, models, routeModels []*NamedModel, rules []*RouteRule) (*LearnModel, error) {
+	if len(models) == 0 {
+		return nil, fmt.Errorf("at least one model is required")
+	}
+	names := make(map[string]struct{}, len(models)+len(routeModels))
+	for _, m := range append(append([]*NamedModel(nil), models...), routeModels...) {
+		if m.Name == "" || m.Model == nil {
+			return nil, fmt.Errorf("model name and model are required")
+		}
+		if _, ok := names[m.Name]; ok {
+			return nil, fmt.Errorf("model %s is duplicated", m.Name)
+		}
+		names[m.Name] = struct{}{}
+	}
+	for _, rule := range rules {
+		if _, ok := names[rule.Model]; !ok {
+			return nil, fmt.Errorf("route rule %s: model %s is not configured", rule.name(), rule.Model)
+		}
+	}
+	l := NewFallbackLearnModel(ctx, models)
+	l.routeModels = routeModels
+	l.rules = rules
+	return l, nil
+}
+
+// route 按规则调整模型的顺序，被选中的模型排在最前，图内运行时记录路由结果
+func (l *LearnModel) route(ctx context.Context, input []*schema.Message) []*NamedModel {
+	if len(l.rules) == 0 {
+		return l.models
+	}
+	in := newRouteInput(ctx, input)
+	decision := &RouteDecision{Step: in.Step, Model: l.models[0].Name}
+	for _, rule := range l.rules {
+		if rule.match(ctx, in) {
+			decision.Rule, decision.Model = rule.name(), rule.Model
+			break
+		}
+	}
+	_ = compose.ProcessState[*state](ctx, func(_ context.Context, s *state) error {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		s.Routes = append(s.Routes, decision)
+		return nil
+	})
+
+	models := make([]*NamedModel, 0, len(l.models)+1)
+	for _, m := range append(append([]*NamedModel(nil), l.models...), l.routeModels...) {
+		if m.Name == decision.Model {
+			models = append(models, m)
+			break
+		}
+	}
+	for _, m := range l.models {
+		if m.Name != decision.Model {
+			models = append(models, m)
+		}
+	}
+	return models
+}
+
+func newRouteInput(ctx context.Context, input []*schema.Message) *RouteInput {
+	in := &RouteInput{Step: currentStep(ctx), Messages: input}
+	if len(input) > 0 {
+		in.AfterToolResult = input[len(input)-1].Role == schema.Tool
+	}
+	for _, msg := range input {
+		in.Tokens += messageTokens(msg)
+		in.HasImage = in.HasImage || hasImage(msg)
+	}
+	if session, ok := GetToolSession(ctx); ok {
+		for _, skill := range session.ActiveSkills() {
+			in.ActiveSkills = append(in.ActiveSkills, skill.Name)
+		}
+	}
+	return in
+}
+
+// messageTokens 估算消息的 token 数，图片等非文本内容不计入
+func messageTokens(msg *schema.Message) int {
+	tokens := estimateTokens(msg.Content) + estimateTokens(msg.ReasoningContent)
+	for _, tc := range msg.ToolCalls {
+		tokens += estimateTokens(tc.Function.Name) + estimateTokens(tc.Function.Arguments)
+	}
+	for _, part := range msg.UserInputMultiContent {
+		tokens += estimateTokens(part.Text)
+	}
+	for _, part := range msg.MultiContent {
+		tokens += estimateTokens(part.Text)
+	}
+	return tokens
+}
+
+func hasImage(msg *schema.Message) bool {
+	for _, part := range msg.UserInputMultiContent {
+		if part.Type == schema.ChatMessagePartTypeImageURL {
+			return true
+		}
+	}
+	for _, part := range msg.MultiContent {
+		if part.Type == schema.ChatMessagePartTypeImageURL {
+			return true
+		}
+	}
+	return false
+}
+
+// GetRouteDecisions 获取本次运行到目前为止的路由结果，只能在图内调用（如 tool、callbacks 中）
+func GetRouteDecisions(ctx context.Context) []*RouteDecision {
+	var decisions []*RouteDecision
+	_ = compose.ProcessState[*state](ctx, func(_ context.Context, s *state) error {
+		s.lock.RLock()
+		defer s.lock.RUnlock()
+		decisions = append(decisions, s.Routes...)
+		return nil
+	})
+	return decisions
+}
+
+// lastRouteDecision step 对应的路由结果，没有配置路由规则时返回 nil
+func lastRouteDecision(ctx context.Context, step int) *RouteDecision {
+	decisions := GetRouteDecisions(ctx)
+	for i := len(decisions) - 1; i >= 0; i-- {
+		if decisions[i].Step == step {
+			return decisions[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package t_eino
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+)
+
+// newRouteTestModel 第一次调用 tool t1，拿到结果后回答 "done by <name>"
+func newRouteTestModel(name string) *scriptedModel {
+	return &scriptedModel{reply: func(in []*schema.Message, tools []*schema.ToolInfo) *schema.Message {
+		if in[len(in)-1].Role == schema.User {
+			return toolCallMessage(newToolCall("c1", "t1", `{"q":"x"}`))
+		}
+		return schema.AssistantMessage("done by "+name, nil)
+	}}
+}
+
+func TestRouteRules(t *testing.T) {
+	ctx := context.Background()
+	yes := true
+	tests := []struct {
+		name       string
+		input      *schema.Message
+		wantRoutes string //每个 step 的 "rule/model"
+		wantModel  string
+	}{
+		{
+			name:       "no rule then after tool result",
+			input:      schema.UserMessage("hi"),
+			wantRoutes: "[/big dispatch/cheap]",
+			wantModel:  "cheap",
+		},
+		{
+			name: "image",
+			input: &schema.Message{Role: schema.User, UserInputMultiContent: []schema.MessageInputPart{
+				{Type: schema.ChatMessagePartTypeText, Text: "what is it"},
+				{Type: schema.ChatMessagePartTypeImageURL, Image: &schema.MessageInputImage{MessagePartCommon: schema.MessagePartCommon{URL: ptrOf("https://example.com/a.png")}}},
+			}},
+			wantRoutes: "[vision/vision vision/vision]",
+			wantModel:  "vision",
+		},
+		{
+			name:       "too many tokens",
+			input:      schema.UserMessage(strings.Repeat("long ", 1000)),
+			wantRoutes: "[/big /big]",
+			wantModel:  "big",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen []*RouteDecision
+			t1 := newFuncTool("t1", func(ctx context.Context, q string) (string, error) {
+				seen = GetRouteDecisions(ctx)
+				return "r", nil
+			})
+			a, err := NewAgent(ctx, &AgentConfig{
+				ToolCallingModel: newRouteTestModel("big"),
+				ModelName:        "big",
+				RouteModels: []*NamedModel{
+					{Name: "cheap", Model: newRouteTestModel("cheap")},
+					{Name: "vision", Model: newRouteTestModel("vision")},
+				},
+				RouteRules: []*RouteRule{
+					{Model: "vision", HasImage: &yes},
+					{Name: "dispatch", Model: "cheap", AfterToolResult: &yes, MaxTokens: 1000},
+				},
+				ToolsConfig: compose.ToolsNodeConfig{Tools: []tool.BaseTool{t1}},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			it, err := a.StreamEvents(ctx, []*schema.Message{tt.input})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer it.Close()
+			var (
+				routes []string
+				final  *schema.Message
+			)
+			for {
+				e, ok := it.Next()
+				if !ok {
+					break
+				}
+				switch e.Type {
+				case EventStepFinished:
+					routes = append(routes, e.Route.Rule+"/"+e.Route.Model)
+				case EventRunFinished:
+					final = e.Message
+				case EventRunError:
+					t.Fatal(e.Err)
+				}
+			}
+			if got := fmt.Sprint(routes); got != tt.wantRoutes {
+				t.Fatalf("got routes %s, want %s", got, tt.wantRoutes)
+			}
+			if GetModelName(final) != tt.wantModel || final.Content != "done by "+tt.wantModel {
+				t.Fatalf("got %q from %q, want answer of %s", final.Content, GetModelName(final), tt.wantModel)
+			}
+			// tool 中能拿到之前 step 的路由结果
+			if len(seen) != 1 || seen[0].Step != 1 {
+				t.Fatalf("got route decisions %v in tool, want the first step", seen)
+			}
+		})
+	}
+}
+
+func TestRouteRulesUnknownModel(t *testing.T) {
+	_, err := NewAgent(context.Background(), &AgentConfig{
+		ToolCallingModel: newRouteTestModel("big"),
+		RouteRules:       []*RouteRule{{Model: "nope"}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "model nope is not configured") {
+		t.Fatalf("got %v, want unknown model error", err)
+	}
+}
+
+func ptrOf[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,322 @@
+package t_eino
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/components/tool/utils"
+	"github.com/cloudwego/eino/schema"
+)
+
+const (
+	SpecialUseSkillToolName = "special_use_skill"
+)
+
+// Skill 一个粒度更大的 tool，其中包含说明和许多小 tool。
+// 激活前大模型只能看到 Name 和 Description 组成的一行简介，
+// 通过 special_use_skill 激活后 Instructions 注入 prompt，Tools 被解锁，子 Skills 变为可见。
+type Skill struct {
+	Name        string
+	Description string //一行简介
+	// Instructions 激活后注入 prompt 的说明
+	Instructions string
+	// Tools 激活后才会绑定给大模型的 tool
+	Tools []tool.BaseTool
+	// Skills 子 skill，只有父 skill 被激活后才可见
+	Skills []*Skill
+	// Loader 可选，激活时才加载说明和资源（例如从 SKILL.md 读取），设置后 Instructions 不再生效
+	Loader SkillLoader
+}
+
+// SkillLoader 延迟加载 skill 的内容，只有 skill 被激活时才会调用
+type SkillLoader interface {
+	// LoadInstructions 返回 skill 的说明
+	LoadInstructions(ctx context.Context) (string, error)
+	// ReadResource 读取 skill 的资源文件，path 为资源的相对路径
+	ReadResource(ctx context.Context, path string) (string, error)
+}
+
+type useSkillArguments struct {
+	Name string `json:"name"`
+}
+
+// walkSkills 深度优先遍历 skill 树
+func walkSkills(skills []*Skill, fn func(s *Skill) error) error {
+	for _, s := range skills {
+		if err := fn(s); err != nil {
+			return err
+		}
+		if err := walkSkills(s.Skills, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkSkills(skills []*Skill) error {
+	names := make(map[string]struct{})
+	return walkSkills(skills, func(s *Skill) error {
+		if s == nil || s.Name == "" {
+			return fmt.Errorf("skill name is empty")
+		}
+		if _, ok := names[s.Name]; ok {
+			return fmt.Errorf("skill %s is duplicated", s.Name)
+		}
+		names[s.Name] = struct{}{}
+		return nil
+	})
+}
+
+// checkSkillTools 检查 skill 的 tool 是否与 tools（config tools、额外的 tool）或其它 skill 的 tool 重名，
+// 同一个 tool 被多个 skill 共用时不算重名。检查通过的 skill tool 会登记到 tools 中。
+func checkSkillTools(ctx context.Context, skills []*Skill, tools map[string]tool.BaseTool) error {
+	return walkSkills(skills, func(skill *Skill) error {
+		for _, tl := range skill.Tools {
+			info, err := tl.Info(ctx)
+			if err != nil {
+				return err
+			}
+			if info.Name == SpecialReadSkillResourceToolName {
+				// 每次 LoadSkills 都会创建一个读取资源的 tool，它们的行为相同
+				continue
+			}
+			if _, ok := reservedToolNames[info.Name]; ok {
+				return fmt.Errorf("tool %s of skill %s uses a reserved special tool name", info.Name, skill.Name)
+			}
+			if existing, ok := tools[info.Name]; ok && !sameTool(existing, tl) {
+				return fmt.Errorf("tool %s of skill %s conflicts with another tool", info.Name, skill.Name)
+			}
+			tools[info.Name] = tl
+		}
+		return nil
+	})
+}
+
+// sameTool 是否为同一个 tool，不可比较的类型视为不同
+func sameTool(a, b tool.BaseTool) bool {
+	ta, tb := reflect.TypeOf(a), reflect.TypeOf(b)
+	return ta == tb && ta.Comparable() && a == b
+}
+
+// AddSkills 为本次运行添加顶层 skill，与 config 中的 skill 或已添加的 skill 重名、skill 的 tool 与已有的 tool 重名时返回错误
+func (s *ToolSession) AddSkills(skills ...*Skill) error {
+	if len(skills) == 0 {
+		return nil
+	}
+	if _, ok := s.toolList.originalTools[SpecialUseSkillToolName]; !ok {
+		return fmt.Errorf("skills are not enabled, set AgentConfig.Skills or DynamicSkills")
+	}
+	// skill 的 tool 不能与本次运行中已有的 tool 重名
+	ctx := context.Background()
+	tools, err := s.runTools(ctx)
+	if err != nil {
+		return err
+	}
+	tm, err := toolsToMap(ctx, tools)
+	if err != nil {
+		return err
+	}
+	if err = checkSkillTools(ctx, skills, tm); err != nil {
+		return err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	all := append(append(append([]*Skill(nil), s.toolList.skills...), s.visibleSkills...), skills...)
+	// visibleSkills 中包含已激活 skill 的子 skill，先去掉重复的指针
+	seen := make(map[*Skill]struct{}, len(all))
+	unique := make([]*Skill, 0, len(all))
+	for _, skill := range all {
+		if _, ok := seen[skill]; ok {
+			continue
+		}
+		seen[skill] = struct{}{}
+		unique = append(unique, skill)
+	}
+	if err := checkSkills(unique); err != nil {
+		return err
+	}
+	s.visibleSkills = append(s.visibleSkills, skills...)
+	return nil
+}
+
+// UseSkill 激活当前可见的 skill，解锁其 tools 并让子 skill 可见
+func (s *ToolSession) UseSkill(ctx context.Context, name string) (*Skill, error) {
+	skill, active := s.findSkill(name)
+	if skill == nil {
+		return nil, fmt.Errorf("skill %s is not exist", name)
+	}
+	if active {
+		return skill, nil
+	}
+
+	instructions := skill.Instructions
+	if skill.Loader != nil {
+		var err error
+		if instructions, err = skill.Loader.LoadInstructions(ctx); err != nil {
+			return nil, fmt.Errorf("load skill %s instructions fail: %w", name, err)
+		}
+	}
+	tm, err := toolsToMap(ctx, skill.Tools)
+	if err != nil {
+		return nil, err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, a := range s.activeSkills {
+		if a == skill {
+			return skill, nil
+		}
+	}
+	for _, tl := range skill.Tools {
+		info, err := tl.Info(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if alive, ok := s.aliveToolsMap[info.Name]; ok {
+			// 注册 skill 时已检查重名，这里只可能是其它 skill 共用的 tool
+			if !sameTool(alive, tl) && info.Name != SpecialReadSkillResourceToolName {
+				return nil, fmt.Errorf("tool %s of skill %s conflicts with alive tool", info.Name, skill.Name)
+			}
+			continue
+		}
+		tokens, err := toolTokenCost(ctx, tm[info.Name])
+		if err != nil {
+			return nil, err
+		}
+		s.aliveToolsMap[info.Name] = tm[info.Name]
+		s.aliveTools = append(s.aliveTools, tm[info.Name])
+		s.toolTokens[info.Name] = tokens
+	}
+	// skill 的 tool 不会被淘汰，超出预算时淘汰动态获取的 tool
+	s.evictLocked("")
+	if s.skillInstructions == nil {
+		s.skillInstructions = make(map[string]string)
+	}
+	s.skillInstructions[skill.Name] = instructions
+	s.activeSkills = append(s.activeSkills, skill)
+	s.visibleSkills = append(s.visibleSkills, skill.Skills...)
+	return skill, nil
+}
+
+// findSkill 在可见的 skill 中查找，active 表示是否已激活
+func (s *ToolSession) findSkill(name string) (skill *Skill, active bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	for _, a := range s.activeSkills {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	for _, visible := range s.visibleSkills {
+		if visible.Name == name {
+			return visible, false
+		}
+	}
+	return nil, false
+}
+
+// GetActiveSkill 获取已激活的 skill
+func (s *ToolSession) GetActiveSkill(name string) (*Skill, bool) {
+	skill, active := s.findSkill(name)
+	if !active {
+		return nil, false
+	}
+	return skill, true
+}
+
+// VisibleSkills 可被激活但尚未激活的 skill
+func (s *ToolSession) VisibleSkills() []*Skill {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	skills := make([]*Skill, 0, len(s.visibleSkills))
+	for _, visible := range s.visibleSkills {
+		active := false
+		for _, a := range s.activeSkills {
+			if a == visible {
+				active = true
+				break
+			}
+		}
+		if !active {
+			skills = append(skills, visible)
+		}
+	}
+	return skills
+}
+
+// ActiveSkills 已激活的 skill，按激活顺序
+func (s *ToolSession) ActiveSkills() []*Skill {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	skills := make([]*Skill, len(s.activeSkills))
+	copy(skills, s.activeSkills)
+	return skills
+}
+
+// skillPrompt 生成注入 prompt 的 skill 信息：未激活 skill 的一行简介和已激活 skill 的说明
+func (s *ToolSession) skillPrompt() string {
+	visible := s.VisibleSkills()
+	active := s.ActiveSkills()
+	if len(visible) == 0 && len(active) == 0 {
+		return ""
+	}
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	var sb strings.Builder
+	if len(visible) > 0 {
+		sb.WriteString(SkillListPrompt)
+		for _, skill := range visible {
+			sb.WriteString(fmt.Sprintf("\n- %s: %s", skill.Name, skill.Description))
+		}
+	}
+	for _, skill := range active {
+		if sb.Len() > 0 {
+			sb.WriteString("\n\n")
+		}
+		sb.WriteString(fmt.Sprintf(ActiveSkillPrompt, skill.Name))
+		sb.WriteString("\n")
+		sb.WriteString(s.skillInstructions[skill.Name])
+	}
+	return sb.String()
+}
+
+// injectSkillPrompt 将 skill 信息作为 system message 插入到开头的 system message 之后，不修改 state 中的消息
+func injectSkillPrompt(session *ToolSession, msgs []*schema.Message) []*schema.Message {
+	if session == nil {
+		return msgs
+	}
+	prompt := session.skillPrompt()
+	if prompt == "" {
+		return msgs
+	}
+	idx := 0
+	for idx < len(msgs) && msgs[idx].Role == schema.System {
+		idx++
+	}
+	result := make([]*schema.Message, 0, len(msgs)+1)
+	result = append(result, msgs[:idx]...)
+	result = append(result, schema.SystemMessage(prompt))
+	result = append(result, msgs[idx:]...)
+	return result
+}
+
+func getUseSkillTool() (tool.BaseTool, error) {
+	inferTool, err := utils.InferTool(SpecialUseSkillToolName, UseSkillToolDescription, func(ctx context.Context, input useSkillArguments) (output string, err error) {
+		s, ok := GetToolSession(ctx)
+		if !ok {
+			return "", fmt.Errorf("tool session is not exist")
+		}
+		if _, err = s.UseSkill(ctx, input.Name); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("use skill %s success", input.Name), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return inferTool, nil
+}
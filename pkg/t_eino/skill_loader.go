@@ -0,0 +1,276 @@
+package t_eino
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/components/tool/utils"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	SkillFileName                    = "SKILL.md"
+	SpecialReadSkillResourceToolName = "special_read_skill_resource"
+
+	frontMatterDelimiter = "---"
+	// maxSkillResourceSize 单个资源文件的最大字节数
+	maxSkillResourceSize = 1 << 20
+)
+
+// skillFrontMatter SKILL.md 开头的 YAML front matter
+type skillFrontMatter struct {
+	Name        string   `yaml:"name"`
+	Description string   `yaml:"description"`
+	Tools       []string `yaml:"tools"` //激活后解锁的 tool 名称
+}
+
+type readSkillResourceArguments struct {
+	Skill string `json:"skill"`
+	Path  string `json:"path"`
+}
+
+// fileSkillLoader 从 skill 目录中延迟读取 SKILL.md 正文和资源文件
+type fileSkillLoader struct {
+	dir string
+}
+
+var _ SkillLoader = &fileSkillLoader{}
+
+// LoadSkills 扫描 dir 下的每个子目录，含有 SKILL.md 的目录即为一个 skill，skill 目录下含有 SKILL.md 的子目录为其子 skill。
+// 加载时只读取 front matter，正文和资源文件在 skill 被激活时才读取，修改 skill 无需重新编译。
+// tools 为 front matter 中 tools 字段可引用的 tool。
+func LoadSkills(ctx context.Context, dir string, tools ...tool.BaseTool) ([]*Skill, error) {
+	tm, err := toolsToMap(ctx, tools)
+	if err != nil {
+		return nil, err
+	}
+	readTool, err := getReadSkillResourceTool()
+	if err != nil {
+		return nil, err
+	}
+	return loadSkillDir(dir, tm, readTool)
+}
+
+func loadSkillDir(dir string, tm map[string]tool.BaseTool, readTool tool.BaseTool) ([]*Skill, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	skills := make([]*Skill, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		skillDir := filepath.Join(dir, entry.Name())
+		if !isSkillDir(skillDir) {
+			continue
+		}
+		skill, err := loadSkill(skillDir, tm, readTool)
+		if err != nil {
+			return nil, err
+		}
+		skills = append(skills, skill)
+	}
+	return skills, nil
+}
+
+func loadSkill(dir string, tm map[string]tool.BaseTool, readTool tool.BaseTool) (*Skill, error) {
+	fm, err := readFrontMatter(filepath.Join(dir, SkillFileName))
+	if err != nil {
+		return nil, fmt.Errorf("load skill %s fail: %w", dir, err)
+	}
+	if fm.Name == "" {
+		fm.Name = filepath.Base(dir)
+	}
+	skillTools := make([]tool.BaseTool, 0, len(fm.Tools)+1)
+	for _, name := range fm.Tools {
+		tl, ok := tm[name]
+		if !ok {
+			return nil, fmt.Errorf("load skill %s fail: tool %s is not exist", fm.Name, name)
+		}
+		skillTools = append(skillTools, tl)
+	}
+	skillTools = append(skillTools, readTool)
+
+	children, err := loadSkillDir(dir, tm, readTool)
+	if err != nil {
+		return nil, err
+	}
+	return &Skill{
+		Name:        fm.Name,
+		Description: fm.Description,
+		Tools:       skillTools,
+		Skills:      children,
+		Loader:      &fileSkillLoader{dir: dir},
+	}, nil
+}
+
+func isSkillDir(dir string) bool {
+	info, err := os.Stat(filepath.Join(dir, SkillFileName))
+	return err == nil && !info.IsDir()
+}
+
+// readFrontMatter 只读取 SKILL.md 的 front matter 部分
+func readFrontMatter(path string) (*skillFrontMatter, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	if !scanner.Scan() || strings.TrimSpace(trimBOM(scanner.Text())) != frontMatterDelimiter {
+		return nil, fmt.Errorf("%s must start with front matter", SkillFileName)
+	}
+	var sb strings.Builder
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == frontMatterDelimiter {
+			fm := &skillFrontMatter{}
+			if err = yaml.Unmarshal([]byte(sb.String()), fm); err != nil {
+				return nil, err
+			}
+			return fm, nil
+		}
+		sb.WriteString(line)
+		sb.WriteString("\n")
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("front matter of %s is not closed", SkillFileName)
+}
+
+// trimBOM 去掉文件开头的 UTF-8 BOM
+func trimBOM(content string) string {
+	return strings.TrimPrefix(content, "\ufeff")
+}
+
+// splitFrontMatter 去掉 front matter，返回正文
+func splitFrontMatter(content string) string {
+	content = trimBOM(content)
+	lines := strings.SplitAfter(content, "\n")
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != frontMatterDelimiter {
+		return content
+	}
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) == frontMatterDelimiter {
+			return strings.TrimSpace(strings.Join(lines[i+1:], ""))
+		}
+	}
+	return content
+}
+
+func (l *fileSkillLoader) LoadInstructions(ctx context.Context) (string, error) {
+	content, err := os.ReadFile(filepath.Join(l.dir, SkillFileName))
+	if err != nil {
+		return "", err
+	}
+	instructions := splitFrontMatter(string(content))
+	resources, err := l.resources()
+	if err != nil {
+		return "", err
+	}
+	if len(resources) == 0 {
+		return instructions, nil
+	}
+	var sb strings.Builder
+	sb.WriteString(instructions)
+	sb.WriteString("\n\n")
+	sb.WriteString(SkillResourcesPrompt)
+	for _, r := range resources {
+		sb.WriteString("\n- ")
+		sb.WriteString(r)
+	}
+	return sb.String(), nil
+}
+
+// resources 列出 skill 目录下除 SKILL.md 和子 skill 目录以外的文件
+func (l *fileSkillLoader) resources() ([]string, error) {
+	var resources []string
+	err := filepath.WalkDir(l.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == l.dir {
+			return nil
+		}
+		if d.IsDir() {
+			if isSkillDir(path) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(l.dir, path)
+		if err != nil {
+			return err
+		}
+		if rel == SkillFileName {
+			return nil
+		}
+		resources = append(resources, filepath.ToSlash(rel))
+		return nil
+	})
+	return resources, err
+}
+
+// ReadResource 读取 skill 自己的资源文件（即 resources 列出的文件），SKILL.md 和子 skill 的文件不可读取，
+// 不允许越出 skill 目录，文件超过 maxSkillResourceSize 时返回错误
+func (l *fileSkillLoader) ReadResource(ctx context.Context, path string) (string, error) {
+	resources, err := l.resources()
+	if err != nil {
+		return "", err
+	}
+	path = filepath.ToSlash(filepath.Clean(filepath.FromSlash(path)))
+	found := false
+	for _, r := range resources {
+		if r == path {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return "", fmt.Errorf("resource %s is not exist", path)
+	}
+	f, err := os.OpenInRoot(l.dir, filepath.FromSlash(path))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	content, err := io.ReadAll(io.LimitReader(f, maxSkillResourceSize+1))
+	if err != nil {
+		return "", err
+	}
+	if len(content) > maxSkillResourceSize {
+		return "", fmt.Errorf("resource %s exceeds %d bytes", path, maxSkillResourceSize)
+	}
+	return string(content), nil
+}
+
+func getReadSkillResourceTool() (tool.BaseTool, error) {
+	inferTool, err := utils.InferTool(SpecialReadSkillResourceToolName, ReadSkillResourceToolDescription, func(ctx context.Context, input readSkillResourceArguments) (output string, err error) {
+		s, ok := GetToolSession(ctx)
+		if !ok {
+			return "", fmt.Errorf("tool session is not exist")
+		}
+		skill, ok := s.GetActiveSkill(input.Skill)
+		if !ok {
+			return "", fmt.Errorf("skill %s is not active", input.Skill)
+		}
+		if skill.Loader == nil {
+			return "", fmt.Errorf("skill %s has no resource", input.Skill)
+		}
+		return skill.Loader.ReadResource(ctx, input.Path)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return inferTool, nil
+}
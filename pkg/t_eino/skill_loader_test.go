@@ -0,0 +1,213 @@
+package t_eino
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// writeSkillFiles 在 dir 下按相对路径写入文件
+func writeSkillFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLoadSkills(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name  string
+		files map[string]string
+		// want 每个 skill 为 "<name>:<description>:<tools>"，子 skill 跟在父 skill 之后
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "front matter with BOM",
+			files: map[string]string{
+				"a/SKILL.md": "\ufeff---\nname: alpha\ndescription: d\ntools: [lookup]\n---\nbody",
+			},
+			want: []string{"alpha:d:lookup," + SpecialReadSkillResourceToolName},
+		},
+		{
+			name: "name defaults to dir and child skills",
+			files: map[string]string{
+				"a/SKILL.md":       "---\ndescription: parent\n---\nbody",
+				"a/child/SKILL.md": "---\nname: c\n---\nchild",
+				"a/docs/ref.md":    "not a skill",
+				"b.md":             "not a skill",
+			},
+			want: []string{"a:parent:" + SpecialReadSkillResourceToolName, "c::" + SpecialReadSkillResourceToolName},
+		},
+		{
+			name:    "unknown tool",
+			files:   map[string]string{"a/SKILL.md": "---\ntools: [nope]\n---\n"},
+			wantErr: "tool nope is not exist",
+		},
+		{
+			name:    "no front matter",
+			files:   map[string]string{"a/SKILL.md": "body"},
+			wantErr: "must start with front matter",
+		},
+		{
+			name:    "front matter not closed",
+			files:   map[string]string{"a/SKILL.md": "---\nname: a\n"},
+			wantErr: "is not closed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeSkillFiles(t, dir, tt.files)
+			skills, err := LoadSkills(ctx, dir, newTestTool("lookup", "d"))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got %v, want error %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			_ = walkSkills(skills, func(s *Skill) error {
+				names := make([]string, 0, len(s.Tools))
+				for _, tl := range s.Tools {
+					info, err := tl.Info(ctx)
+					if err != nil {
+						return err
+					}
+					names = append(names, info.Name)
+				}
+				got = append(got, s.Name+":"+s.Description+":"+strings.Join(names, ","))
+				return nil
+			})
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSkillResources(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	writeSkillFiles(t, dir, map[string]string{
+		"a/SKILL.md":       "\ufeff---\nname: a\n---\nbody",
+		"a/ref.md":         "ref",
+		"a/docs/guide.md":  "guide",
+		"a/big.txt":        strings.Repeat("x", maxSkillResourceSize+1),
+		"a/child/SKILL.md": "---\nname: c\n---\nchild",
+		"a/child/data.md":  "child data",
+		"secret.md":        "secret",
+	})
+	skills, err := LoadSkills(ctx, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loader := skills[0].Loader
+
+	instructions, err := loader.LoadInstructions(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 子 skill 的文件不属于父 skill 的资源
+	if want := "body\n\n" + SkillResourcesPrompt + "\n- big.txt\n- docs/guide.md\n- ref.md"; instructions != want {
+		t.Fatalf("got instructions %q, want %q", instructions, want)
+	}
+
+	tests := []struct {
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{path: "ref.md", want: "ref"},
+		{path: "./docs/../ref.md", want: "ref"},
+		{path: "docs/guide.md", want: "guide"},
+		{path: "big.txt", wantErr: true},
+		{path: SkillFileName, wantErr: true},
+		{path: "child/data.md", wantErr: true},
+		{path: "../secret.md", wantErr: true},
+		{path: "../a/ref.md", wantErr: true},
+		{path: "missing.md", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := loader.ReadResource(ctx, tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got err %v, want err %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSkillLoaderInAgent(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	writeSkillFiles(t, dir, map[string]string{
+		"a/SKILL.md": "---\nname: a\ndescription: d\n---\nbody",
+		"a/ref.md":   "ref",
+		"b/SKILL.md": "---\nname: b\ndescription: d\n---\nbody",
+		"b/ref.md":   "other ref",
+	})
+	skills, err := LoadSkills(ctx, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		skill   string
+		want    string
+		wantErr bool
+	}{
+		{name: "active skill", skill: "a", want: "r=ref"},
+		{name: "inactive skill", skill: "b", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 激活 a 后读取 tt.skill 的资源
+			llm := &scriptedModel{reply: func(in []*schema.Message, tools []*schema.ToolInfo) *schema.Message {
+				last := in[len(in)-1]
+				switch {
+				case last.Role == schema.User:
+					return toolCallMessage(newToolCall("u", SpecialUseSkillToolName, `{"name":"a"}`))
+				case last.ToolCallID == "u":
+					return toolCallMessage(newToolCall("r", SpecialReadSkillResourceToolName, fmt.Sprintf(`{"skill":%q,"path":"ref.md"}`, tt.skill)))
+				}
+				return schema.AssistantMessage(toolResults(in[len(in)-1:]), nil)
+			}}
+			a, err := NewAgent(ctx, &AgentConfig{ToolCallingModel: llm, Skills: skills})
+			if err != nil {
+				t.Fatal(err)
+			}
+			msg, err := a.Generate(ctx, []*schema.Message{schema.UserMessage("hi")})
+			if tt.wantErr {
+				if !IsToolExecutionFailed(err) {
+					t.Fatalf("got %v, want tool execution failed", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if msg.Content != tt.want {
+				t.Fatalf("got %q, want %q", msg.Content, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package t_eino
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+)
+
+func TestUseSkill(t *testing.T) {
+	ctx := context.Background()
+	weather := &Skill{
+		Name:         "weather",
+		Description:  "weather skill",
+		Instructions: "use lookup",
+		Tools:        []tool.BaseTool{newTestTool("lookup", "d")},
+		Skills:       []*Skill{{Name: "forecast", Description: "forecast skill", Instructions: "use predict", Tools: []tool.BaseTool{newTestTool("predict", "d")}}},
+	}
+	// 依次激活 weather 和 forecast，调用解锁的 tool，记录每次调用 ChatModel 时看到的 tool 和 skill prompt
+	var steps []string
+	llm := &scriptedModel{reply: func(in []*schema.Message, tools []*schema.ToolInfo) *schema.Message {
+		prompt := ""
+		for _, msg := range in {
+			if msg.Role == schema.System {
+				prompt = msg.Content
+			}
+		}
+		steps = append(steps, fmt.Sprintf("%v %q", toolNames(tools), prompt))
+		switch len(steps) {
+		case 1:
+			return toolCallMessage(newToolCall("u1", SpecialUseSkillToolName, `{"name":"weather"}`))
+		case 2:
+			return toolCallMessage(newToolCall("u2", SpecialUseSkillToolName, `{"name":"forecast"}`))
+		case 3:
+			return toolCallMessage(newToolCall("c1", "lookup", `{"q":"x"}`), newToolCall("c2", "predict", `{"q":"y"}`))
+		}
+		return schema.AssistantMessage(toolResults(in), nil)
+	}}
+	a, err := NewAgent(ctx, &AgentConfig{
+		ToolCallingModel: llm,
+		ToolsConfig:      compose.ToolsNodeConfig{Tools: []tool.BaseTool{newTestTool("config", "d")}},
+		Skills:           []*Skill{weather},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := a.Generate(ctx, []*schema.Message{schema.UserMessage("hi")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "u1=use skill weather success; u2=use skill forecast success; c1=lookup got x; c2=predict got y"; msg.Content != want {
+		t.Fatalf("got %q, want %q", msg.Content, want)
+	}
+
+	list := SkillListPrompt + "\n- "
+	want := []string{
+		fmt.Sprintf("[config %s] %q", SpecialUseSkillToolName, list+"weather: weather skill"),
+		fmt.Sprintf("[config %s lookup] %q", SpecialUseSkillToolName, list+"forecast: forecast skill\n\n"+fmt.Sprintf(ActiveSkillPrompt, "weather")+"\nuse lookup"),
+	}
+	for i, w := range want {
+		if steps[i] != w {
+			t.Fatalf("step %d: got %s, want %s", i+1, steps[i], w)
+		}
+	}
+	if !strings.HasPrefix(steps[2], fmt.Sprintf("[config %s lookup predict]", SpecialUseSkillToolName)) {
+		t.Fatalf("step 3: got %s", steps[2])
+	}
+}
+
+func TestSkillToolConflict(t *testing.T) {
+	ctx := context.Background()
+	shared := newTestTool("shared", "d")
+	tests := []struct {
+		name    string
+		config  AgentConfig
+		skills  []*Skill //通过 WithSkills 注入
+		wantErr bool
+	}{
+		{
+			name: "config tool",
+			config: AgentConfig{
+				ToolsConfig: compose.ToolsNodeConfig{Tools: []tool.BaseTool{newTestTool("a", "d")}},
+				Skills:      []*Skill{{Name: "s", Tools: []tool.BaseTool{newTestTool("a", "d")}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "extra tool",
+			config: AgentConfig{
+				ExtraTools: []tool.BaseTool{newTestTool("b", "d")},
+				Skills:     []*Skill{{Name: "s", Tools: []tool.BaseTool{newTestTool("b", "d")}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "special tool",
+			config: AgentConfig{
+				Skills: []*Skill{{Name: "s", Tools: []tool.BaseTool{newTestTool(SpecialGetToolToolName, "d")}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "tool shared by skills",
+			config: AgentConfig{
+				Skills: []*Skill{{Name: "s", Tools: []tool.BaseTool{shared}}, {Name: "s2", Tools: []tool.BaseTool{shared}}},
+			},
+		},
+		{
+			name: "duplicated skill",
+			config: AgentConfig{
+				Skills: []*Skill{{Name: "s"}, {Name: "p", Skills: []*Skill{{Name: "s"}}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "dynamic skill conflicts with config tool",
+			config: AgentConfig{
+				DynamicSkills: true,
+				ToolsConfig:   compose.ToolsNodeConfig{Tools: []tool.BaseTool{newTestTool("a", "d")}},
+			},
+			skills:  []*Skill{{Name: "d", Tools: []tool.BaseTool{newTestTool("a", "d")}}},
+			wantErr: true,
+		},
+		{
+			name:    "dynamic skill conflicts with config skill",
+			config:  AgentConfig{Skills: []*Skill{{Name: "s"}}},
+			skills:  []*Skill{{Name: "s"}},
+			wantErr: true,
+		},
+		{
+			name:    "dynamic skills disabled",
+			skills:  []*Skill{{Name: "d"}},
+			wantErr: true,
+		},
+		{
+			name:   "dynamic skill",
+			config: AgentConfig{DynamicSkills: true},
+			skills: []*Skill{{Name: "d", Tools: []tool.BaseTool{newTestTool("c", "d")}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := tt.config
+			config.ToolCallingModel = newReplyModel("done")
+			a, err := NewAgent(ctx, &config)
+			if err == nil {
+				var opts []Option
+				if len(tt.skills) > 0 {
+					opts = append(opts, WithSkills(tt.skills...))
+				}
+				_, err = a.Generate(ctx, []*schema.Message{schema.UserMessage("hi")}, opts...)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got err %v, want err %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package t_eino
+
+import (
+	"context"
+	"sync"
+
+	"github.com/cloudwego/eino/callbacks"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+	ub "github.com/cloudwego/eino/utils/callbacks"
+)
+
+// StreamChunk Stream 输出的一项
+type StreamChunk struct {
+	// Message ChatModel 的一次输出或一个 tool 的结果，读取完毕或不再读取时需 Close
+	Message *schema.StreamReader[*schema.Message]
+
+	// Done 为 true 时是最后一项，Output 为最终结果，Err 为运行错误，正常结束时为 nil
+	Done   bool
+	Output *schema.Message
+	Err    error
+}
+
+// StreamIterator 依次输出运行过程中的 StreamChunk，最后一项的 Done 为 true。
+// 读到最后一项前不再读取时需调用 Close，会取消运行并等待后台的 goroutine 退出。
+type StreamIterator struct {
+	q *runQueue[*StreamChunk]
+}
+
+// Next 阻塞直到有新的一项，读完最后一项或 Close 后返回 false
+func (it *StreamIterator) Next() (*StreamChunk, bool) {
+	return it.q.next()
+}
+
+// Close 取消运行，丢弃未读取的项，并等待后台的 goroutine 退出，可重复调用
+func (it *StreamIterator) Close() {
+	it.q.close()
+}
+
+// runQueue 后台运行写入、调用方读取的无界队列，写入不会阻塞运行
+type runQueue[T any] struct {
+	cancel  context.CancelFunc
+	done    chan struct{} //后台运行结束后关闭
+	discard func(T)       //丢弃未读取的项时调用，用于关闭其中的流
+
+	lock     sync.Mutex
+	cond     *sync.Cond
+	items    []T
+	finished bool
+	closed   bool
+}
+
+func newRunQueue[T any](discard func(T)) *runQueue[T] {
+	q := &runQueue[T]{done: make(chan struct{}), discard: discard}
+	q.cond = sync.NewCond(&q.lock)
+	return q
+}
+
+func (q *runQueue[T]) next() (T, bool) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	for len(q.items) == 0 && !q.finished && !q.closed {
+		q.cond.Wait()
+	}
+	if q.closed || len(q.items) == 0 {
+		var zero T
+		return zero, false
+	}
+	item := q.items[0]
+	q.items = q.items[1:]
+	return item, true
+}
+
+func (q *runQueue[T]) close() {
+	q.cancel()
+	q.lock.Lock()
+	pending := q.items
+	q.items = nil
+	q.closed = true
+	q.cond.Broadcast()
+	q.lock.Unlock()
+	for _, item := range pending {
+		q.discard(item)
+	}
+	<-q.done
+}
+
+func (q *runQueue[T]) push(item T) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if q.closed {
+		q.discard(item)
+		return
+	}
+	q.items = append(q.items, item)
+	q.cond.Signal()
+}
+
+// finish 加入最后的项，只能在后台的 goroutine 结束时调用一次
+func (q *runQueue[T]) finish(last ...T) {
+	for _, item := range last {
+		q.push(item)
+	}
+	q.lock.Lock()
+	q.finished = true
+	q.cond.Broadcast()
+	q.lock.Unlock()
+	q.cancel()
+	close(q.done)
+}
+
+func discardStreamChunk(chunk *StreamChunk) {
+	if chunk.Message != nil {
+		chunk.Message.Close()
+	}
+}
+
+// streamChunkHandler 通过 callbacks 收集 ChatModel 的输出和 tool 的结果
+func streamChunkHandler(q *runQueue[*StreamChunk]) callbacks.Handler {
+	modelHandler := &ub.ModelCallbackHandler{
+		OnEnd: func(ctx context.Context, _ *callbacks.RunInfo, output *model.CallbackOutput) context.Context {
+			q.push(&StreamChunk{Message: schema.StreamReaderFromArray([]*schema.Message{output.Message})})
+			return ctx
+		},
+		OnEndWithStreamOutput: func(ctx context.Context, _ *callbacks.RunInfo, output *schema.StreamReader[*model.CallbackOutput]) context.Context {
+			q.push(&StreamChunk{Message: schema.StreamReaderWithConvert(output, func(o *model.CallbackOutput) (*schema.Message, error) {
+				return o.Message, nil
+			})})
+			return ctx
+		},
+	}
+	toolHandler := &ub.ToolCallbackHandler{
+		OnEnd: func(ctx context.Context, info *callbacks.RunInfo, output *tool.CallbackOutput) context.Context {
+			msg := schema.ToolMessage(output.Response, compose.GetToolCallID(ctx), schema.WithToolName(info.Name))
+			q.push(&StreamChunk{Message: schema.StreamReaderFromArray([]*schema.Message{msg})})
+			return ctx
+		},
+		OnEndWithStreamOutput: func(ctx context.Context, info *callbacks.RunInfo, output *schema.StreamReader[*tool.CallbackOutput]) context.Context {
+			toolCallID := compose.GetToolCallID(ctx)
+			q.push(&StreamChunk{Message: schema.StreamReaderWithConvert(output, func(o *tool.CallbackOutput) (*schema.Message, error) {
+				return schema.ToolMessage(o.Response, toolCallID, schema.WithToolName(info.Name)), nil
+			})})
+			return ctx
+		},
+	}
+	return ub.NewHandlerHelper().ChatModel(modelHandler).Tool(toolHandler).Handler()
+}
@@ -0,0 +1,161 @@
+package t_eino
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+)
+
+// newEventTestModel 第一次调用 echo，拿到结果后回答 "final"
+func newEventTestModel(q string) *scriptedModel {
+	return &scriptedModel{reply: func(in []*schema.Message, tools []*schema.ToolInfo) *schema.Message {
+		if len(in) == 1 {
+			return toolCallMessage(newToolCall("c1", "echo", fmt.Sprintf(`{"q":%q}`, q)))
+		}
+		return schema.AssistantMessage("final", nil)
+	}}
+}
+
+// newEventTestAgent echo 的参数为 fail 时失败
+func newEventTestAgent(t *testing.T, ctx context.Context, llm model.ToolCallingChatModel) *Agent {
+	t.Helper()
+	echo := newFuncTool("echo", func(ctx context.Context, q string) (string, error) {
+		if q == "fail" {
+			return "", errors.New("boom")
+		}
+		return "echo " + q, nil
+	})
+	a, err := NewAgent(ctx, &AgentConfig{ToolCallingModel: llm, ToolsConfig: compose.ToolsNodeConfig{Tools: []tool.BaseTool{echo}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func TestStream(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		q    string
+		// want 每项为一个 chunk 中消息的 "<role>:<content>"，Done 为 "done:<output>" 或 "error:<err>"
+		want []string
+	}{
+		{
+			name: "tool call",
+			q:    "hello",
+			want: []string{"assistant:", "tool:echo hello", "assistant:final", "done:final"},
+		},
+		{
+			name: "tool error",
+			q:    "fail",
+			want: []string{"assistant:", "error:" + ErrToolExecution.Error()},
+		},
+	}
+	for _, tt := range tests {
+		for _, m := range testModels(newEventTestModel(tt.q)) {
+			t.Run(tt.name+"/"+m.name, func(t *testing.T) {
+				it, err := newEventTestAgent(t, ctx, m.model).Stream(ctx, []*schema.Message{schema.UserMessage("hi")})
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer it.Close()
+				var got []string
+				for {
+					chunk, ok := it.Next()
+					if !ok {
+						break
+					}
+					if chunk.Done {
+						if chunk.Err != nil {
+							if !IsToolExecutionFailed(chunk.Err) {
+								t.Fatalf("got error %v", chunk.Err)
+							}
+							got = append(got, "error:"+ErrToolExecution.Error())
+						} else {
+							got = append(got, "done:"+chunk.Output.Content)
+						}
+						continue
+					}
+					msgs, err := readAll(chunk.Message)
+					if err != nil {
+						t.Fatal(err)
+					}
+					msg, err := schema.ConcatMessages(msgs)
+					if err != nil {
+						t.Fatal(err)
+					}
+					got = append(got, string(msg.Role)+":"+msg.Content)
+				}
+				if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+					t.Fatalf("got %v, want %v", got, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestStreamClose(t *testing.T) {
+	ctx := context.Background()
+	// 第二次调用 ChatModel 时阻塞，Close 需要取消运行并等待退出
+	block := make(chan struct{})
+	llm := &scriptedModel{reply: func(in []*schema.Message, tools []*schema.ToolInfo) *schema.Message {
+		if len(in) > 1 {
+			<-block
+		}
+		return toolCallMessage(newToolCall(fmt.Sprint("c", len(in)), "echo", `{"q":"x"}`))
+	}}
+	a, err := NewAgent(ctx, &AgentConfig{ToolCallingModel: llm, ToolsConfig: compose.ToolsNodeConfig{Tools: []tool.BaseTool{newTestTool("echo", "d")}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	it, err := a.Stream(ctx, []*schema.Message{schema.UserMessage("hi")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunk, ok := it.Next()
+	if !ok || chunk.Done {
+		t.Fatalf("got %+v, want the first chunk", chunk)
+	}
+	chunk.Message.Close()
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		close(block)
+	}()
+	done := make(chan struct{})
+	go func() {
+		it.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close does not return")
+	}
+	if _, ok = it.Next(); ok {
+		t.Fatal("got chunk after Close")
+	}
+}
+
+func readAll(sr *schema.StreamReader[*schema.Message]) ([]*schema.Message, error) {
+	defer sr.Close()
+	var msgs []*schema.Message
+	for {
+		msg, err := sr.Recv()
+		if err == io.EOF {
+			return msgs, nil
+		}
+		if err != nil {
+			return msgs, err
+		}
+		msgs = append(msgs, msg)
+	}
+}
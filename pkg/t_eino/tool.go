@@ -2,31 +2,75 @@ package t_eino
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
-	utils2 "github.com/birdy/agent/utils"
-	"github.com/cloudwego/eino/components/model"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/cloudwego/eino/components/embedding"
 	"github.com/cloudwego/eino/components/tool"
 	"github.com/cloudwego/eino/components/tool/utils"
+	"github.com/cloudwego/eino/compose"
 	"github.com/cloudwego/eino/schema"
 )
 
 const (
-	SpecialGetToolToolName = "special_get_tool"
+	SpecialGetToolToolName     = "special_get_tool"
+	SpecialReleaseToolToolName = "special_release_tool"
 )
 
-// 全局 tool
+// 全局 tool，构建后只读，可被多个运行共享
 type ToolList struct {
-	chatModel     model.ToolCallingChatModel
-	aliveTools    []tool.BaseTool //被大模型“看到”的工具列表
+	tools         []tool.BaseTool //保持 config 中的顺序，保证每次绑定给大模型的 tool 顺序稳定
 	originalTools map[string]tool.BaseTool
+	skills        []*Skill                 //顶层 skill
+	extraTools    map[string]tool.BaseTool //每次运行默认注入的额外 tool
+	retriever     *toolRetriever
+	toolTokens    map[string]int //config tools 和 special tools 的 schema 估算 token 数
+	maxAliveTools int
+	maxToolTokens int
+	// extraSpecialTools special_get_tool、special_search_tools 和 special_release_tool，运行中有额外的 tool 时才绑定
+	extraSpecialTools []namedTool
+}
+
+// ToolSession 单次运行的 tool 状态，每次 Generate/Stream 各自持有一份，互不影响
+type ToolSession struct {
+	aliveTools    []tool.BaseTool //被大模型“看到”的工具列表
 	aliveToolsMap map[string]tool.BaseTool
 	extraToolsMap map[string]tool.BaseTool
+	visibleSkills []*Skill //大模型可见的 skill，子 skill 在父 skill 激活后加入
+	activeSkills  []*Skill
+	// skillInstructions 已激活 skill 的说明，激活时加载一次
+	skillInstructions map[string]string
+	searchIndex       *bm25Index //额外 tool 的搜索索引，首次搜索时构建，extraToolsMap 变化时置空
+	// dynamicTools 运行中从额外 tool 获取的 tool，按最近使用排序，最久未使用的在前，超出预算时从前往后淘汰
+	dynamicTools []string
+	toolTokens   map[string]int //alive tools 的 schema 估算 token 数
+	toolList     *ToolList
+	runID        string //设置了 CheckPointStore 时用于保存和恢复运行
+	// onMessages state 中的消息每次更新后调用，用于 CompressAgent、推荐问题获取本次运行的完整上下文
+	onMessages []func(msgs []*schema.Message)
+	lock       sync.RWMutex
+}
+
+type namedTool struct {
+	name string
+	tool tool.BaseTool
 }
 
+type toolSessionKey struct{}
+
 type getToolArguments struct {
 	Name string `json:"name"`
 }
 
+type releaseToolArguments struct {
+	Name string `json:"name"`
+}
+
+// toolsToMap 同一批 tool 中有重名时返回错误
 func toolsToMap(ctx context.Context, tools []tool.BaseTool) (map[string]tool.BaseTool, error) {
 	m := make(map[string]tool.BaseTool, len(tools))
 	for _, tool := range tools {
@@ -34,81 +78,590 @@ func toolsToMap(ctx context.Context, tools []tool.BaseTool) (map[string]tool.Bas
 		if err != nil {
 			return nil, err
 		}
+		if _, ok := m[info.Name]; ok {
+			return nil, fmt.Errorf("duplicate tool name %s", info.Name)
+		}
 		m[info.Name] = tool
 	}
 	return m, nil
 }
 
-func NewToolList(ctx context.Context, originalTools []tool.BaseTool, chatModel model.ToolCallingChatModel) (*ToolList, error) {
+// reservedToolNames special tools 的名称，config tools 和额外的 tool 不能使用
+var reservedToolNames = map[string]struct{}{
+	SpecialGetToolToolName:           {},
+	SpecialSearchToolsToolName:       {},
+	SpecialReleaseToolToolName:       {},
+	SpecialUseSkillToolName:          {},
+	SpecialReadSkillResourceToolName: {},
+}
+
+// checkToolNames 检查 tm 中的 tool 是否使用了保留名称，或与 config tools（包含 special tools）重名，kind 用于错误信息
+func checkToolNames(tm map[string]tool.BaseTool, configTools map[string]tool.BaseTool, kind string) error {
+	for name := range tm {
+		if _, ok := reservedToolNames[name]; ok {
+			return fmt.Errorf("%s %s uses a reserved special tool name", kind, name)
+		}
+		if _, ok := configTools[name]; ok {
+			return fmt.Errorf("%s %s conflicts with a config tool", kind, name)
+		}
+	}
+	return nil
+}
+
+// ToolListConfig is the config for ToolList.
+type ToolListConfig struct {
+	// Tools config tools，始终被大模型“看到”
+	Tools []tool.BaseTool
+	// Skills 顶层 skill
+	Skills []*Skill
+	// DynamicSkills 允许通过 WithSkills 在运行时注入 skill，Skills 为空时同样会注册 special_use_skill
+	DynamicSkills bool
+	// ExtraTools 每次运行默认注入的额外 tool，等同于每次运行都使用 WithTools 注入
+	ExtraTools []tool.BaseTool
+	// Embedder 可选，设置后会为额外的 tool 计算向量，并在第一次调用 ChatModel 前按最新的 user message 预先获取最相关的 tool
+	Embedder embedding.Embedder
+	// PreloadTopK 预先获取的 tool 数量，默认 3
+	PreloadTopK int
+	// PreloadMinScore 预先获取的最低相似度，低于该值的 tool 不会被获取，相似度不大于 0 的 tool 总是不会被获取
+	PreloadMinScore float64
+	// MaxAliveTools 可选，alive tools 的最大数量（包含 config tools），超出时淘汰最久未使用的动态获取的 tool
+	MaxAliveTools int
+	// MaxToolTokens 可选，alive tools 的 schema 估算 token 总数上限，超出时淘汰最久未使用的动态获取的 tool
+	MaxToolTokens int
+}
+
+func NewToolList(ctx context.Context, config *ToolListConfig) (*ToolList, error) {
+	originalTools := config.Tools
+	skills := config.Skills
+	if err := checkSkills(skills); err != nil {
+		return nil, err
+	}
 	tm, err := toolsToMap(ctx, originalTools)
 	if err != nil {
 		return nil, err
 	}
-	t := &ToolList{
-		chatModel:     chatModel,
-		originalTools: tm,
+	if err = checkToolNames(tm, nil, "config tool"); err != nil {
+		return nil, err
 	}
-	specialTool, err := getSpecialTool(t)
+	extraTools, err := toolsToMap(ctx, config.ExtraTools)
 	if err != nil {
 		return nil, err
 	}
-	t.originalTools[SpecialGetToolToolName] = specialTool
+	if err = checkToolNames(extraTools, tm, "extra tool"); err != nil {
+		return nil, err
+	}
+	known := make(map[string]tool.BaseTool, len(tm)+len(extraTools))
+	for _, m := range []map[string]tool.BaseTool{tm, extraTools} {
+		for name, tl := range m {
+			known[name] = tl
+		}
+	}
+	if err = checkSkillTools(ctx, skills, known); err != nil {
+		return nil, err
+	}
+	specialTools, err := getExtraSpecialTools()
+	if err != nil {
+		return nil, err
+	}
+	t := &ToolList{
+		tools:             append(make([]tool.BaseTool, 0, len(originalTools)+len(specialTools)+1), originalTools...),
+		originalTools:     tm,
+		skills:            skills,
+		extraTools:        extraTools,
+		extraSpecialTools: specialTools,
+		retriever:         newToolRetriever(config),
+		toolTokens:        make(map[string]int, len(tm)+len(specialTools)),
+		maxAliveTools:     config.MaxAliveTools,
+		maxToolTokens:     config.MaxToolTokens,
+	}
+	for _, st := range specialTools {
+		if t.toolTokens[st.name], err = toolTokenCost(ctx, st.tool); err != nil {
+			return nil, err
+		}
+		if len(extraTools) > 0 {
+			tm[st.name] = st.tool
+			t.tools = append(t.tools, st.tool)
+		}
+	}
+	if t.retriever != nil && len(extraTools) > 0 {
+		if _, err = t.retriever.buildIndex(ctx, extraTools); err != nil {
+			return nil, err
+		}
+	}
+	if len(skills) > 0 || config.DynamicSkills {
+		useSkillTool, err := getUseSkillTool()
+		if err != nil {
+			return nil, err
+		}
+		tm[SpecialUseSkillToolName] = useSkillTool
+		t.tools = append(t.tools, useSkillTool)
+	}
+	for name, tl := range tm {
+		if t.toolTokens[name], err = toolTokenCost(ctx, tl); err != nil {
+			return nil, err
+		}
+	}
 	return t, nil
 }
 
-// 每次运行开始前初始化
-func (t *ToolList) Init() {
-	t.aliveTools = utils2.MapToSlice[tool.BaseTool](t.originalTools)
-	t.aliveToolsMap = t.originalTools
+// Tools 返回 config tools（包含 special tools，special_get_tool 等只在 config 中有额外的 tool 时包含）
+func (t *ToolList) Tools() []tool.BaseTool {
+	tools := make([]tool.BaseTool, len(t.tools))
+	copy(tools, t.tools)
+	return tools
 }
 
-func (t *ToolList) SetExtraTools(ctx context.Context, tools ...tool.BaseTool) error {
+// NewSession 每次运行开始前创建，alive tools 为 config tools 的拷贝
+func (t *ToolList) NewSession() *ToolSession {
+	aliveToolsMap := make(map[string]tool.BaseTool, len(t.originalTools))
+	for name, tl := range t.originalTools {
+		aliveToolsMap[name] = tl
+	}
+	toolTokens := make(map[string]int, len(t.originalTools))
+	for name := range t.originalTools {
+		toolTokens[name] = t.toolTokens[name]
+	}
+	extraToolsMap := make(map[string]tool.BaseTool, len(t.extraTools))
+	for name, tl := range t.extraTools {
+		extraToolsMap[name] = tl
+	}
+	return &ToolSession{
+		aliveTools:    t.Tools(),
+		aliveToolsMap: aliveToolsMap,
+		extraToolsMap: extraToolsMap,
+		visibleSkills: append([]*Skill(nil), t.skills...),
+		toolTokens:    toolTokens,
+		toolList:      t,
+	}
+}
+
+func withToolSession(ctx context.Context, s *ToolSession) context.Context {
+	return context.WithValue(ctx, toolSessionKey{}, s)
+}
+
+func toolSessionFromCtx(ctx context.Context) *ToolSession {
+	s, _ := ctx.Value(toolSessionKey{}).(*ToolSession)
+	return s
+}
+
+// GetToolSession 获取当前运行的 ToolSession，只能在图内（节点、tool）调用
+func GetToolSession(ctx context.Context) (*ToolSession, bool) {
+	var s *ToolSession
+	_ = compose.ProcessState[*state](ctx, func(ctx context.Context, st *state) error {
+		s = st.getToolSession(ctx)
+		return nil
+	})
+	return s, s != nil
+}
+
+// SetExtraTools 替换额外的 tool，需在运行开始前（如 Option 中）调用，
+// 运行开始后 ToolsNode 可执行的 tool 已确定，之后设置的 tool 在本次运行中无法执行
+func (s *ToolSession) SetExtraTools(ctx context.Context, tools ...tool.BaseTool) error {
 	tm, err := toolsToMap(ctx, tools)
 	if err != nil {
 		return err
 	}
-	t.extraToolsMap = tm
+	if err = checkToolNames(tm, s.toolList.originalTools, "extra tool"); err != nil {
+		return err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.extraToolsMap = tm
+	s.searchIndex = nil
+	s.enableExtraSpecialToolsLocked()
 	return nil
 }
 
-func (t *ToolList) GetTools() []tool.BaseTool {
-	return t.aliveTools
+// addExtraTools 追加额外的 tool，与 config tools、已有的额外 tool（包含已获取的）或 skill 的 tool 同名时返回错误，
+// 同一个 tool 重复注入不算重名
+func (s *ToolSession) addExtraTools(ctx context.Context, tm map[string]tool.BaseTool) error {
+	if err := checkToolNames(tm, s.toolList.originalTools, "extra tool"); err != nil {
+		return err
+	}
+	tools, err := s.runTools(ctx)
+	if err != nil {
+		return err
+	}
+	existing, err := toolsToMap(ctx, tools)
+	if err != nil {
+		return err
+	}
+	for name, tl := range tm {
+		if e, ok := existing[name]; ok && !sameTool(e, tl) {
+			return fmt.Errorf("extra tool %s conflicts with an existing tool", name)
+		}
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for name, tl := range tm {
+		s.extraToolsMap[name] = tl
+	}
+	s.searchIndex = nil
+	s.enableExtraSpecialToolsLocked()
+	return nil
 }
 
-func (t *ToolList) GetToolByName(name string) (tool.BaseTool, bool) {
-	if tool, ok := t.aliveToolsMap[name]; ok {
-		return tool, true
+// enableExtraSpecialToolsLocked 有额外的 tool 时才绑定 special_get_tool 等，避免没有额外 tool 的 agent 多出无用的 tool schema
+func (s *ToolSession) enableExtraSpecialToolsLocked() {
+	if len(s.extraToolsMap) == 0 {
+		return
+	}
+	for _, st := range s.toolList.extraSpecialTools {
+		if _, ok := s.aliveToolsMap[st.name]; ok {
+			continue
+		}
+		s.aliveToolsMap[st.name] = st.tool
+		s.aliveTools = append(s.aliveTools, st.tool)
+		s.toolTokens[st.name] = s.toolList.toolTokens[st.name]
 	}
+}
 
-	if tool, ok := t.extraToolsMap[name]; ok {
-		delete(t.extraToolsMap, name)
-		t.aliveToolsMap[name] = tool
-		t.aliveTools = append(t.aliveTools, tool)
-		return tool, true
+func (s *ToolSession) GetTools() []tool.BaseTool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	tools := make([]tool.BaseTool, len(s.aliveTools))
+	copy(tools, s.aliveTools)
+	return tools
+}
+
+// AliveToolNames 当前被大模型“看到”的 tool 名称
+func (s *ToolSession) AliveToolNames() []string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	names := make([]string, 0, len(s.aliveToolsMap))
+	for name := range s.aliveToolsMap {
+		names = append(names, name)
 	}
+	return names
+}
 
-	return nil, false
+// ToolInfos 当前 alive tools 的 schema，ChatModel 每次调用前据此重新绑定
+func (s *ToolSession) ToolInfos(ctx context.Context) ([]*schema.ToolInfo, error) {
+	tools := s.GetTools()
+	infos := make([]*schema.ToolInfo, 0, len(tools))
+	for _, tl := range tools {
+		info, err := tl.Info(ctx)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
 }
 
-func getSpecialTool(t *ToolList) (tool.BaseTool, error) {
-	inferTool, err := utils.InferTool(SpecialGetToolToolName, GetToolToolDescription, func(ctx context.Context, input getToolArguments) (output string, err error) {
-		_, ok := t.GetToolByName(input.Name)
+func (s *ToolSession) getAliveTool(name string) (tool.BaseTool, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	tl, ok := s.aliveToolsMap[name]
+	return tl, ok
+}
+
+// GetToolByName 获取 tool，额外的 tool 会被加入 alive tools，超出预算时淘汰最久未使用的动态获取的 tool
+func (s *ToolSession) GetToolByName(ctx context.Context, name string) (tool.BaseTool, bool) {
+	tl, _, ok, err := s.loadTool(ctx, name)
+	return tl, ok && err == nil
+}
+
+// loadTool 返回获取到的 tool 和因超出预算被淘汰的 tool 名称
+func (s *ToolSession) loadTool(ctx context.Context, name string) (tool.BaseTool, []string, bool, error) {
+	s.lock.RLock()
+	tl, alive := s.aliveToolsMap[name]
+	if !alive {
+		tl = s.extraToolsMap[name]
+	}
+	s.lock.RUnlock()
+	if alive {
+		s.touch(name)
+		return tl, nil, true, nil
+	}
+	if tl == nil {
+		return nil, nil, false, nil
+	}
+	tokens, err := toolTokenCost(ctx, tl)
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, ok := s.aliveToolsMap[name]; ok {
+		return s.aliveToolsMap[name], nil, true, nil
+	}
+	if _, ok := s.extraToolsMap[name]; !ok {
+		return nil, nil, false, nil
+	}
+	delete(s.extraToolsMap, name)
+	// 搜索索引只包含 extraToolsMap 中的 tool，需要重建
+	s.searchIndex = nil
+	s.aliveToolsMap[name] = tl
+	s.aliveTools = append(s.aliveTools, tl)
+	s.toolTokens[name] = tokens
+	s.dynamicTools = append(s.dynamicTools, name)
+	return tl, s.evictLocked(name), true, nil
+}
+
+// touch 将动态获取的 tool 标记为最近使用
+func (s *ToolSession) touch(name string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for i, n := range s.dynamicTools {
+		if n == name {
+			s.dynamicTools = append(append(s.dynamicTools[:i:i], s.dynamicTools[i+1:]...), name)
+			return
+		}
+	}
+}
+
+// ReleaseTool 释放动态获取的 tool，释放后可再次通过 special_get_tool 获取，config tools 不可释放
+func (s *ToolSession) ReleaseTool(name string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, n := range s.dynamicTools {
+		if n == name {
+			s.releaseLocked(name)
+			return nil
+		}
+	}
+	if _, ok := s.aliveToolsMap[name]; ok {
+		return fmt.Errorf("tool %s can not be released", name)
+	}
+	return fmt.Errorf("tool %s is not alive", name)
+}
+
+func (s *ToolSession) releaseLocked(name string) {
+	tl := s.aliveToolsMap[name]
+	delete(s.aliveToolsMap, name)
+	delete(s.toolTokens, name)
+	for i, alive := range s.aliveTools {
+		if alive == tl {
+			s.aliveTools = append(s.aliveTools[:i:i], s.aliveTools[i+1:]...)
+			break
+		}
+	}
+	for i, n := range s.dynamicTools {
+		if n == name {
+			s.dynamicTools = append(s.dynamicTools[:i:i], s.dynamicTools[i+1:]...)
+			break
+		}
+	}
+	s.extraToolsMap[name] = tl
+	s.searchIndex = nil
+}
+
+// evictLocked 超出预算时按最久未使用淘汰动态获取的 tool，keep 不会被淘汰
+func (s *ToolSession) evictLocked(keep string) []string {
+	var evicted []string
+	for s.overBudgetLocked() {
+		victim := ""
+		for _, n := range s.dynamicTools {
+			if n != keep {
+				victim = n
+				break
+			}
+		}
+		if victim == "" {
+			break
+		}
+		s.releaseLocked(victim)
+		evicted = append(evicted, victim)
+	}
+	return evicted
+}
+
+func (s *ToolSession) overBudgetLocked() bool {
+	if s.toolList == nil {
+		return false
+	}
+	if s.toolList.maxAliveTools > 0 && len(s.aliveTools) > s.toolList.maxAliveTools {
+		return true
+	}
+	if s.toolList.maxToolTokens > 0 {
+		var total int
+		for _, tokens := range s.toolTokens {
+			total += tokens
+		}
+		return total > s.toolList.maxToolTokens
+	}
+	return false
+}
+
+// toolTokenCost 估算 tool schema 占用的 token 数
+func toolTokenCost(ctx context.Context, tl tool.BaseTool) (int, error) {
+	info, err := tl.Info(ctx)
+	if err != nil {
+		return 0, err
+	}
+	text := info.Name + info.Desc
+	if info.ParamsOneOf != nil {
+		sc, err := info.ParamsOneOf.ToJSONSchema()
+		if err != nil {
+			return 0, err
+		}
+		params, err := json.Marshal(sc)
+		if err != nil {
+			return 0, err
+		}
+		text += string(params)
+	}
+	return estimateTokens(text), nil
+}
+
+// estimateTokens 粗略估算 token 数：ASCII 约 4 个字符一个 token，其它字符一个字一个 token
+func estimateTokens(text string) int {
+	var ascii, other int
+	for _, r := range text {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return (ascii+3)/4 + other
+}
+
+// runTools 本次运行中 ToolsNode 可能执行的 tool：alive tools、额外的 tool 和所有 skill 的 tool，
+// 通过 compose.WithToolList 交给 ToolsNode，使动态获取的 tool 与 config tools 一样经过 ToolCallMiddlewares 和 ToolArgumentsHandler。
+// 未被大模型“看到”的 tool 由 aliveToolsMiddleware 拦截。
+func (s *ToolSession) runTools(ctx context.Context) ([]tool.BaseTool, error) {
+	s.lock.RLock()
+	tools := append([]tool.BaseTool(nil), s.aliveTools...)
+	extraNames := make([]string, 0, len(s.extraToolsMap))
+	for name := range s.extraToolsMap {
+		extraNames = append(extraNames, name)
+	}
+	sort.Strings(extraNames)
+	for _, name := range extraNames {
+		tools = append(tools, s.extraToolsMap[name])
+	}
+	skills := append(append(append([]*Skill(nil), s.toolList.skills...), s.visibleSkills...), s.activeSkills...)
+	s.lock.RUnlock()
+
+	seen := make(map[string]struct{}, len(tools))
+	result := make([]tool.BaseTool, 0, len(tools))
+	add := func(tl tool.BaseTool) error {
+		info, err := tl.Info(ctx)
+		if err != nil {
+			return err
+		}
+		if _, ok := seen[info.Name]; ok {
+			return nil
+		}
+		seen[info.Name] = struct{}{}
+		result = append(result, tl)
+		return nil
+	}
+	for _, tl := range tools {
+		if err := add(tl); err != nil {
+			return nil, err
+		}
+	}
+	err := walkSkills(skills, func(skill *Skill) error {
+		for _, tl := range skill.Tools {
+			if err := add(tl); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// aliveToolsMiddleware 拦截不在 alive tools 中的 tool call，交给 fallback（即 config 中原有的 UnknownToolsHandler），
+// 并将动态获取的 tool 标记为最近使用
+func aliveToolsMiddleware(fallback func(ctx context.Context, name, input string) (string, error)) compose.ToolMiddleware {
+	check := func(ctx context.Context, input *compose.ToolInput) (string, bool, error) {
+		s, ok := GetToolSession(ctx)
 		if !ok {
-			return "", fmt.Errorf("tool %s is not exist", input.Name)
+			return "", false, nil
+		}
+		if _, ok = s.getAliveTool(input.Name); ok {
+			s.touch(input.Name)
+			return "", false, nil
 		}
-		tools := t.GetTools()
-		infos := make([]*schema.ToolInfo, len(tools))
-		for _, baseTool := range tools {
-			info, err := baseTool.Info(ctx)
-			if err != nil {
-				return "", err
+		result, err := unknownToolsHandler(fallback)(ctx, input.Name, input.Arguments)
+		return result, true, err
+	}
+	return compose.ToolMiddleware{
+		Invokable: func(next compose.InvokableToolEndpoint) compose.InvokableToolEndpoint {
+			return func(ctx context.Context, input *compose.ToolInput) (*compose.ToolOutput, error) {
+				result, handled, err := check(ctx, input)
+				if err != nil {
+					return nil, err
+				}
+				if handled {
+					return &compose.ToolOutput{Result: result}, nil
+				}
+				return next(ctx, input)
+			}
+		},
+		Streamable: func(next compose.StreamableToolEndpoint) compose.StreamableToolEndpoint {
+			return func(ctx context.Context, input *compose.ToolInput) (*compose.StreamToolOutput, error) {
+				result, handled, err := check(ctx, input)
+				if err != nil {
+					return nil, err
+				}
+				if handled {
+					return &compose.StreamToolOutput{Result: schema.StreamReaderFromArray([]string{result})}, nil
+				}
+				return next(ctx, input)
 			}
-			infos = append(infos, info)
+		},
+	}
+}
+
+// unknownToolsHandler 作为 ToolsNode 的 UnknownToolsHandler，处理本次运行中不存在的 tool，
+// 被拒绝的 tool call 直接返回拒绝原因，其余交给 fallback，没有 fallback 时返回 ErrToolNotFound
+func unknownToolsHandler(fallback func(ctx context.Context, name, input string) (string, error)) func(ctx context.Context, name, input string) (string, error) {
+	return func(ctx context.Context, name, input string) (string, error) {
+		if result, ok := rejectedToolCall(ctx, compose.GetToolCallID(ctx)); ok {
+			return result, nil
 		}
-		_, err = t.chatModel.WithTools(infos)
+		if fallback != nil {
+			return fallback(ctx, name, input)
+		}
+		return "", &AgentError{Kind: ErrToolNotFound, Step: currentStep(ctx), ToolCallID: compose.GetToolCallID(ctx), ToolName: name}
+	}
+}
+
+func getExtraSpecialTools() ([]namedTool, error) {
+	getTool, err := getSpecialTool()
+	if err != nil {
+		return nil, err
+	}
+	searchTool, err := getSearchToolsTool()
+	if err != nil {
+		return nil, err
+	}
+	releaseTool, err := getReleaseTool()
+	if err != nil {
+		return nil, err
+	}
+	return []namedTool{
+		{name: SpecialGetToolToolName, tool: getTool},
+		{name: SpecialSearchToolsToolName, tool: searchTool},
+		{name: SpecialReleaseToolToolName, tool: releaseTool},
+	}, nil
+}
+
+func getSpecialTool() (tool.BaseTool, error) {
+	inferTool, err := utils.InferTool(SpecialGetToolToolName, GetToolToolDescription, func(ctx context.Context, input getToolArguments) (output string, err error) {
+		s, ok := GetToolSession(ctx)
+		if !ok {
+			return "", fmt.Errorf("tool session is not exist")
+		}
+		_, evicted, ok, err := s.loadTool(ctx, input.Name)
 		if err != nil {
 			return "", err
 		}
+		if !ok {
+			return "", fmt.Errorf("tool %s is not exist", input.Name)
+		}
+		if len(evicted) > 0 {
+			return fmt.Sprintf("get tool %s success, released tools: %s", input.Name, strings.Join(evicted, ", ")), nil
+		}
 		return fmt.Sprintf("get tool %s success", input.Name), nil
 	})
 	if err != nil {
@@ -116,3 +669,26 @@ func getSpecialTool(t *ToolList) (tool.BaseTool, error) {
 	}
 	return inferTool, nil
 }
+
+func getReleaseTool() (tool.BaseTool, error) {
+	inferTool, err := utils.InferTool(SpecialReleaseToolToolName, ReleaseToolToolDescription, func(ctx context.Context, input releaseToolArguments) (output string, err error) {
+		s, ok := GetToolSession(ctx)
+		if !ok {
+			return "", fmt.Errorf("tool session is not exist")
+		}
+		if err = s.ReleaseTool(input.Name); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("release tool %s success", input.Name), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return inferTool, nil
+}
+
+func (s *ToolSession) notifyMessages(msgs []*schema.Message) {
+	for _, fn := range s.onMessages {
+		fn(msgs)
+	}
+}
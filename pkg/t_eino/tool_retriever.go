@@ -0,0 +1,239 @@
+package t_eino
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/cloudwego/eino/components/embedding"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+)
+
+const (
+	defaultToolPreloadTopK = 3
+	defaultHashEmbedderDim = 256
+	// defaultToolVectorCacheSize 缓存的 tool 向量数量，config 中的额外 tool 另外计入
+	defaultToolVectorCacheSize = 1024
+)
+
+// toolRetriever 按向量相似度为额外的 tool 做检索，tool 向量按 tool 的搜索文本缓存，多次运行共享
+type toolRetriever struct {
+	embedder embedding.Embedder
+	topK     int
+	minScore float64
+
+	vectors *lruCache[[]float64] //key 为 toolSearchText
+}
+
+// vectorIndex 内存向量索引，暴力计算余弦相似度
+type vectorIndex struct {
+	names        []string
+	descriptions []string
+	vectors      [][]float64
+}
+
+func newToolRetriever(config *ToolListConfig) *toolRetriever {
+	if config.Embedder == nil {
+		return nil
+	}
+	topK := config.PreloadTopK
+	if topK <= 0 {
+		topK = defaultToolPreloadTopK
+	}
+	return &toolRetriever{
+		embedder: config.Embedder,
+		topK:     topK,
+		minScore: config.PreloadMinScore,
+		vectors:  newLRUCache[[]float64](defaultToolVectorCacheSize + len(config.ExtraTools)),
+	}
+}
+
+// buildIndex 为 tools 计算向量，已缓存的不会重复计算。
+// 额外的 tool 注册时（NewToolList、WithTools）即调用一次，预先计算向量。
+func (r *toolRetriever) buildIndex(ctx context.Context, tools map[string]tool.BaseTool) (*vectorIndex, error) {
+	idx := &vectorIndex{}
+	texts := make([]string, 0, len(tools))
+	for _, tl := range tools {
+		info, err := tl.Info(ctx)
+		if err != nil {
+			return nil, err
+		}
+		idx.names = append(idx.names, info.Name)
+		idx.descriptions = append(idx.descriptions, info.Desc)
+		texts = append(texts, toolSearchText(info))
+	}
+
+	idx.vectors = make([][]float64, len(texts))
+	missing := make([]int, 0, len(texts))
+	for i, text := range texts {
+		if vector, ok := r.vectors.get(text); ok {
+			idx.vectors[i] = vector
+		} else {
+			missing = append(missing, i)
+		}
+	}
+	if len(missing) == 0 {
+		return idx, nil
+	}
+
+	missingTexts := make([]string, len(missing))
+	for i, j := range missing {
+		missingTexts[i] = texts[j]
+	}
+	vectors, err := r.embedder.EmbedStrings(ctx, missingTexts)
+	if err != nil {
+		return nil, fmt.Errorf("embed tools fail: %w", err)
+	}
+	if len(vectors) != len(missing) {
+		return nil, fmt.Errorf("embed tools fail: expect %d vectors, got %d", len(missing), len(vectors))
+	}
+	for i, j := range missing {
+		idx.vectors[j] = vectors[i]
+		r.vectors.put(texts[j], vectors[i])
+	}
+	return idx, nil
+}
+
+func (v *vectorIndex) search(query []float64, topK int, minScore float64) []ToolSearchResult {
+	results := make([]ToolSearchResult, 0, len(v.names))
+	for i := range v.names {
+		score := cosineSimilarity(query, v.vectors[i])
+		// 相似度为 0 的 tool 与 query 无关，即使 minScore 为 0 也不返回
+		if score <= 0 || score < minScore {
+			continue
+		}
+		results = append(results, ToolSearchResult{Name: v.names[i], Description: v.descriptions[i], Score: score})
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score == results[j].Score {
+			return results[i].Name < results[j].Name
+		}
+		return results[i].Score > results[j].Score
+	})
+	if len(results) > topK {
+		results = results[:topK]
+	}
+	return results
+}
+
+func cosineSimilarity(a, b []float64) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += a[i] * b[i]
+		na += a[i] * a[i]
+		nb += b[i] * b[i]
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+// PreloadTools 按 query 的向量预先获取最相关的额外 tool，未设置 Embedder 时不做任何事
+func (s *ToolSession) PreloadTools(ctx context.Context, query string) error {
+	if s == nil || s.toolList == nil || s.toolList.retriever == nil || strings.TrimSpace(query) == "" {
+		return nil
+	}
+	r := s.toolList.retriever
+
+	s.lock.RLock()
+	extra := make(map[string]tool.BaseTool, len(s.extraToolsMap))
+	for name, tl := range s.extraToolsMap {
+		extra[name] = tl
+	}
+	s.lock.RUnlock()
+	if len(extra) == 0 {
+		return nil
+	}
+
+	idx, err := r.buildIndex(ctx, extra)
+	if err != nil {
+		return err
+	}
+	vectors, err := r.embedder.EmbedStrings(ctx, []string{query})
+	if err != nil {
+		return fmt.Errorf("embed query fail: %w", err)
+	}
+	if len(vectors) != 1 {
+		return fmt.Errorf("embed query fail: expect 1 vector, got %d", len(vectors))
+	}
+	for _, result := range idx.search(vectors[0], r.topK, r.minScore) {
+		if _, _, _, err = s.loadTool(ctx, result.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// latestUserQuery 最新一条 user message 的文本
+func latestUserQuery(msgs []*schema.Message) string {
+	for i := len(msgs) - 1; i >= 0; i-- {
+		if msgs[i] == nil || msgs[i].Role != schema.User {
+			continue
+		}
+		var sb strings.Builder
+		sb.WriteString(msgs[i].Content)
+		for _, part := range msgs[i].UserInputMultiContent {
+			if part.Type == schema.ChatMessagePartTypeText {
+				sb.WriteString(" ")
+				sb.WriteString(part.Text)
+			}
+		}
+		return sb.String()
+	}
+	return ""
+}
+
+// HashEmbedder 基于特征哈希的本地 Embedder，结果确定且无需网络，适合测试和离线场景
+type HashEmbedder struct {
+	Dim int
+}
+
+var _ embedding.Embedder = &HashEmbedder{}
+
+func NewHashEmbedder(dim int) *HashEmbedder {
+	if dim <= 0 {
+		dim = defaultHashEmbedderDim
+	}
+	return &HashEmbedder{Dim: dim}
+}
+
+func (h *HashEmbedder) EmbedStrings(ctx context.Context, texts []string, opts ...embedding.Option) ([][]float64, error) {
+	dim := h.Dim
+	if dim <= 0 {
+		dim = defaultHashEmbedderDim
+	}
+	vectors := make([][]float64, len(texts))
+	for i, text := range texts {
+		vector := make([]float64, dim)
+		for _, term := range tokenize(text) {
+			hash := fnv.New64a()
+			_, _ = hash.Write([]byte(term))
+			sum := hash.Sum64()
+			sign := 1.0
+			if sum&(1<<63) != 0 {
+				sign = -1.0
+			}
+			vector[sum%uint64(dim)] += sign
+		}
+		var norm float64
+		for _, x := range vector {
+			norm += x * x
+		}
+		if norm > 0 {
+			norm = math.Sqrt(norm)
+			for j := range vector {
+				vector[j] /= norm
+			}
+		}
+		vectors[i] = vector
+	}
+	return vectors, nil
+}
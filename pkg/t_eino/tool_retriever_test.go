@@ -0,0 +1,146 @@
+package t_eino
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/cloudwego/eino/components/embedding"
+	"github.com/cloudwego/eino/components/tool"
+)
+
+// countingEmbedder 记录被计算向量的文本数量
+type countingEmbedder struct {
+	HashEmbedder
+	texts atomic.Int64
+}
+
+func (c *countingEmbedder) EmbedStrings(ctx context.Context, texts []string, opts ...embedding.Option) ([][]float64, error) {
+	c.texts.Add(int64(len(texts)))
+	return c.HashEmbedder.EmbedStrings(ctx, texts, opts...)
+}
+
+func retrieverTestTools() []tool.BaseTool {
+	return []tool.BaseTool{
+		newTestTool("get_weather", "query weather forecast of a city"),
+		newTestTool("send_email", "send an email to someone"),
+		newTestTool("read_file", "read content of a local file"),
+	}
+}
+
+func TestVectorIndexSearch(t *testing.T) {
+	ctx := context.Background()
+	r := newToolRetriever(&ToolListConfig{Embedder: NewHashEmbedder(0)})
+	idx, err := r.buildIndex(ctx, mustToolsToMap(t, retrieverTestTools()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		topK     int
+		minScore float64
+		want     []string
+	}{
+		{name: "match", query: "weather forecast", topK: 3, want: []string{"get_weather"}},
+		{name: "zero score excluded", query: "zzz qqq", topK: 3, want: nil},
+		{name: "min score", query: "weather forecast", topK: 3, minScore: 0.99, want: nil},
+		{name: "top k", query: "weather forecast email", topK: 1, want: []string{"get_weather"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := r.embedder.EmbedStrings(ctx, []string{tt.query})
+			if err != nil {
+				t.Fatal(err)
+			}
+			results := idx.search(query[0], tt.topK, tt.minScore)
+			var got []string
+			for _, result := range results {
+				if result.Score <= 0 || result.Score < tt.minScore {
+					t.Errorf("result %s has score %f", result.Name, result.Score)
+				}
+				got = append(got, result.Name)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToolRetrieverPrecompute(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		// withTools 通过 WithTools 注入额外的 tool，否则通过 config 注入
+		withTools bool
+	}{
+		{name: "config extra tools"},
+		{name: "with tools", withTools: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			embedder := &countingEmbedder{}
+			config := &ToolListConfig{Embedder: embedder}
+			if !tt.withTools {
+				config.ExtraTools = retrieverTestTools()
+			}
+			tl, err := NewToolList(ctx, config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s := tl.NewSession()
+			if tt.withTools {
+				o, err := WithTools(ctx, retrieverTestTools()...)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, err = (&Agent{}).getAgentOption(s, o); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := embedder.texts.Load(); got != 3 {
+				t.Fatalf("embedded %d texts at registration, want 3", got)
+			}
+
+			// 运行时只计算 query 的向量
+			if err = s.PreloadTools(ctx, "weather forecast"); err != nil {
+				t.Fatal(err)
+			}
+			if got := embedder.texts.Load(); got != 4 {
+				t.Fatalf("embedded %d texts after preload, want 4", got)
+			}
+			if _, ok := s.aliveToolsMap["get_weather"]; !ok {
+				t.Fatalf("get_weather is not preloaded, alive tools: %v", s.aliveToolsMap)
+			}
+			if _, ok := s.aliveToolsMap["send_email"]; ok {
+				t.Fatalf("unrelated tool send_email is preloaded")
+			}
+		})
+	}
+}
+
+func TestToolRetrieverCacheBounded(t *testing.T) {
+	ctx := context.Background()
+	r := newToolRetriever(&ToolListConfig{Embedder: NewHashEmbedder(0)})
+	for i := 0; i < defaultToolVectorCacheSize+10; i++ {
+		name := fmt.Sprintf("tool_%d", i)
+		if _, err := r.buildIndex(ctx, mustToolsToMap(t, []tool.BaseTool{newTestTool(name, "desc of "+name)})); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := r.vectors.len(); got != defaultToolVectorCacheSize {
+		t.Fatalf("cached %d vectors, want %d", got, defaultToolVectorCacheSize)
+	}
+}
+
+func mustToolsToMap(t *testing.T, tools []tool.BaseTool) map[string]tool.BaseTool {
+	t.Helper()
+	m, err := toolsToMap(context.Background(), tools)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
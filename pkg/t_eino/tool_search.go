@@ -0,0 +1,228 @@
+package t_eino
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/components/tool/utils"
+	"github.com/cloudwego/eino/schema"
+	"github.com/eino-contrib/jsonschema"
+)
+
+const (
+	SpecialSearchToolsToolName = "special_search_tools"
+
+	defaultToolSearchTopK = 5
+
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+type searchToolsArguments struct {
+	Query string `json:"query" jsonschema:"description=描述需要的能力的关键词"`
+	TopK  int    `json:"top_k,omitempty" jsonschema:"description=返回的数量，默认为5"`
+}
+
+// ToolSearchResult 一个搜索命中的额外 tool
+type ToolSearchResult struct {
+	Name        string
+	Description string
+	Score       float64
+}
+
+type bm25Doc struct {
+	name        string
+	description string
+	tf          map[string]int
+	length      int
+}
+
+// bm25Index 额外 tool 的本地 BM25 索引，文档为 tool 的名称、描述和参数
+type bm25Index struct {
+	docs   []bm25Doc
+	df     map[string]int
+	avgLen float64
+}
+
+func newBM25Index(ctx context.Context, tools map[string]tool.BaseTool) (*bm25Index, error) {
+	idx := &bm25Index{
+		docs: make([]bm25Doc, 0, len(tools)),
+		df:   make(map[string]int),
+	}
+	var totalLen int
+	for _, tl := range tools {
+		info, err := tl.Info(ctx)
+		if err != nil {
+			return nil, err
+		}
+		terms := tokenize(toolSearchText(info))
+		tf := make(map[string]int, len(terms))
+		for _, term := range terms {
+			tf[term]++
+		}
+		for term := range tf {
+			idx.df[term]++
+		}
+		totalLen += len(terms)
+		idx.docs = append(idx.docs, bm25Doc{name: info.Name, description: info.Desc, tf: tf, length: len(terms)})
+	}
+	if len(idx.docs) > 0 {
+		idx.avgLen = float64(totalLen) / float64(len(idx.docs))
+	}
+	// 保证同分时结果稳定
+	sort.Slice(idx.docs, func(i, j int) bool { return idx.docs[i].name < idx.docs[j].name })
+	return idx, nil
+}
+
+// search 返回得分最高的 topK 个文档，filter 返回 false 的文档会被跳过
+func (idx *bm25Index) search(query string, topK int, filter func(name string) bool) []ToolSearchResult {
+	terms := tokenize(query)
+	n := float64(len(idx.docs))
+	results := make([]ToolSearchResult, 0, len(idx.docs))
+	for _, doc := range idx.docs {
+		if filter != nil && !filter(doc.name) {
+			continue
+		}
+		var score float64
+		for _, term := range terms {
+			f := float64(doc.tf[term])
+			if f == 0 {
+				continue
+			}
+			df := float64(idx.df[term])
+			idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+			score += idf * f * (bm25K1 + 1) / (f + bm25K1*(1-bm25B+bm25B*float64(doc.length)/idx.avgLen))
+		}
+		if score > 0 {
+			results = append(results, ToolSearchResult{Name: doc.name, Description: doc.description, Score: score})
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	if len(results) > topK {
+		results = results[:topK]
+	}
+	return results
+}
+
+// toolSearchText tool 名称出现两次以提高权重
+func toolSearchText(info *schema.ToolInfo) string {
+	var sb strings.Builder
+	sb.WriteString(info.Name)
+	sb.WriteString(" ")
+	sb.WriteString(info.Name)
+	sb.WriteString(" ")
+	sb.WriteString(info.Desc)
+	if info.ParamsOneOf != nil {
+		if sc, err := info.ParamsOneOf.ToJSONSchema(); err == nil {
+			writeSchemaText(&sb, sc)
+		}
+	}
+	return sb.String()
+}
+
+func writeSchemaText(sb *strings.Builder, sc *jsonschema.Schema) {
+	if sc == nil {
+		return
+	}
+	sb.WriteString(" ")
+	sb.WriteString(sc.Description)
+	if sc.Properties != nil {
+		for pair := sc.Properties.Oldest(); pair != nil; pair = pair.Next() {
+			sb.WriteString(" ")
+			sb.WriteString(pair.Key)
+			writeSchemaText(sb, pair.Value)
+		}
+	}
+	writeSchemaText(sb, sc.Items)
+}
+
+// tokenize 英文按单词切分（snake_case、camelCase 会被拆开），中文按单字和相邻双字切分
+func tokenize(text string) []string {
+	var (
+		terms []string
+		word  []rune
+		prev  rune
+	)
+	flush := func() {
+		if len(word) > 0 {
+			terms = append(terms, strings.ToLower(string(word)))
+			word = word[:0]
+		}
+	}
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			flush()
+			terms = append(terms, string(r))
+			if unicode.Is(unicode.Han, prev) {
+				terms = append(terms, string([]rune{prev, r}))
+			}
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if unicode.IsUpper(r) && unicode.IsLower(prev) {
+				flush()
+			}
+			word = append(word, r)
+		default:
+			flush()
+		}
+		prev = r
+	}
+	flush()
+	return terms
+}
+
+// SearchTools 在尚未获取的额外 tool 中搜索
+func (s *ToolSession) SearchTools(ctx context.Context, query string, topK int) ([]ToolSearchResult, error) {
+	if topK <= 0 {
+		topK = defaultToolSearchTopK
+	}
+	s.lock.Lock()
+	if s.searchIndex == nil {
+		idx, err := newBM25Index(ctx, s.extraToolsMap)
+		if err != nil {
+			s.lock.Unlock()
+			return nil, err
+		}
+		s.searchIndex = idx
+	}
+	idx := s.searchIndex
+	s.lock.Unlock()
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return idx.search(query, topK, func(name string) bool {
+		_, ok := s.extraToolsMap[name]
+		return ok
+	}), nil
+}
+
+func getSearchToolsTool() (tool.BaseTool, error) {
+	inferTool, err := utils.InferTool(SpecialSearchToolsToolName, SearchToolsToolDescription, func(ctx context.Context, input searchToolsArguments) (output string, err error) {
+		s, ok := GetToolSession(ctx)
+		if !ok {
+			return "", fmt.Errorf("tool session is not exist")
+		}
+		results, err := s.SearchTools(ctx, input.Query, input.TopK)
+		if err != nil {
+			return "", err
+		}
+		if len(results) == 0 {
+			return SearchToolsEmptyResult, nil
+		}
+		var sb strings.Builder
+		sb.WriteString(SearchToolsResultPrompt)
+		for _, r := range results {
+			sb.WriteString(fmt.Sprintf("\n- %s: %s", r.Name, r.Description))
+		}
+		return sb.String(), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return inferTool, nil
+}
@@ -0,0 +1,86 @@
+package t_eino
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestSearchTools(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name  string
+		query string
+		// prepare 搜索前对 session 的操作
+		prepare func(t *testing.T, s *ToolSession)
+		want    []string
+	}{
+		{name: "match", query: "weather", want: []string{"get_weather"}},
+		{name: "match description", query: "email someone", want: []string{"send_email"}},
+		{name: "no match", query: "database", want: nil},
+		{
+			name:  "loaded tool excluded",
+			query: "weather",
+			prepare: func(t *testing.T, s *ToolSession) {
+				mustLoadTool(t, s, "get_weather")
+			},
+			want: nil,
+		},
+		{
+			// 获取后构建的索引不包含该 tool，释放后需要重建索引
+			name:  "released tool searchable",
+			query: "weather",
+			prepare: func(t *testing.T, s *ToolSession) {
+				mustLoadTool(t, s, "get_weather")
+				if _, err := s.SearchTools(ctx, "weather", 0); err != nil {
+					t.Fatal(err)
+				}
+				if err := s.ReleaseTool("get_weather"); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: []string{"get_weather"},
+		},
+		{
+			name:  "loaded after index built",
+			query: "email",
+			prepare: func(t *testing.T, s *ToolSession) {
+				if _, err := s.SearchTools(ctx, "email", 0); err != nil {
+					t.Fatal(err)
+				}
+				mustLoadTool(t, s, "send_email")
+			},
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tl, err := NewToolList(ctx, &ToolListConfig{ExtraTools: retrieverTestTools()})
+			if err != nil {
+				t.Fatal(err)
+			}
+			s := tl.NewSession()
+			if tt.prepare != nil {
+				tt.prepare(t, s)
+			}
+			results, err := s.SearchTools(ctx, tt.query, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, result := range results {
+				got = append(got, result.Name)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func mustLoadTool(t *testing.T, s *ToolSession, name string) {
+	t.Helper()
+	if _, _, ok, err := s.loadTool(context.Background(), name); err != nil || !ok {
+		t.Fatalf("load tool %s: ok=%v err=%v", name, ok, err)
+	}
+}
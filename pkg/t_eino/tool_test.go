@@ -0,0 +1,273 @@
+package t_eino
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+)
+
+func TestWithToolsConflict(t *testing.T) {
+	ctx := context.Background()
+	configExtra := newTestTool("extra", "config extra tool")
+	tests := []struct {
+		name  string
+		tools []tool.BaseTool
+		// prepare 注入前对 session 的操作
+		prepare func(t *testing.T, s *ToolSession)
+		wantErr bool
+	}{
+		{name: "new tool", tools: []tool.BaseTool{newTestTool("other", "d")}},
+		{name: "same tool again", tools: []tool.BaseTool{configExtra}},
+		{name: "config tool", tools: []tool.BaseTool{newTestTool("config", "d")}, wantErr: true},
+		{name: "config extra tool", tools: []tool.BaseTool{newTestTool("extra", "d")}, wantErr: true},
+		{name: "special tool", tools: []tool.BaseTool{newTestTool(SpecialGetToolToolName, "d")}, wantErr: true},
+		{name: "skill tool", tools: []tool.BaseTool{newTestTool("skill_tool", "d")}, wantErr: true},
+		{
+			name:  "loaded extra tool",
+			tools: []tool.BaseTool{newTestTool("extra", "d")},
+			prepare: func(t *testing.T, s *ToolSession) {
+				mustLoadTool(t, s, "extra")
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tl, err := NewToolList(ctx, &ToolListConfig{
+				Tools:      []tool.BaseTool{newTestTool("config", "d")},
+				ExtraTools: []tool.BaseTool{configExtra},
+				Skills:     []*Skill{{Name: "skill", Description: "d", Tools: []tool.BaseTool{newTestTool("skill_tool", "d")}}},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			s := tl.NewSession()
+			if tt.prepare != nil {
+				tt.prepare(t, s)
+			}
+			o, err := WithTools(ctx, tt.tools...)
+			if err == nil {
+				_, err = (&Agent{}).getAgentOption(s, o)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got err %v, want err %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if got := s.extraToolsMap["extra"]; got != nil && !sameTool(got, configExtra) {
+					t.Fatal("config extra tool is overwritten")
+				}
+			}
+		})
+	}
+}
+
+func TestToolEviction(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		// ops 依次执行，"+x" 获取 x，"-x" 释放 x
+		ops         []string
+		wantDynamic []string
+		wantEvicted []string
+		wantErr     bool
+	}{
+		{name: "within budget", ops: []string{"+a", "+b"}, wantDynamic: []string{"a", "b"}},
+		{name: "evict least recently used", ops: []string{"+a", "+b", "+c"}, wantDynamic: []string{"b", "c"}, wantEvicted: []string{"a"}},
+		{name: "touch on reload", ops: []string{"+a", "+b", "+a", "+c"}, wantDynamic: []string{"a", "c"}, wantEvicted: []string{"b"}},
+		{name: "release", ops: []string{"+a", "+b", "-a", "+c"}, wantDynamic: []string{"b", "c"}},
+		{name: "release config tool", ops: []string{"-config"}, wantErr: true},
+		{name: "release tool not alive", ops: []string{"-a"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tl, err := NewToolList(ctx, &ToolListConfig{
+				Tools:      []tool.BaseTool{newTestTool("config", "d")},
+				ExtraTools: []tool.BaseTool{newTestTool("a", "d"), newTestTool("b", "d"), newTestTool("c", "d")},
+				// config tool 和三个 special tool 占 4 个
+				MaxAliveTools: 6,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			s := tl.NewSession()
+			var evicted []string
+			for _, op := range tt.ops {
+				if op[0] == '-' {
+					err = s.ReleaseTool(op[1:])
+					continue
+				}
+				_, ev, ok, loadErr := s.loadTool(ctx, op[1:])
+				if loadErr != nil || !ok {
+					t.Fatalf("load tool %s: ok=%v err=%v", op[1:], ok, loadErr)
+				}
+				evicted = append(evicted, ev...)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got err %v, want err %v", err, tt.wantErr)
+			}
+			if fmt.Sprint(s.dynamicTools) != fmt.Sprint(tt.wantDynamic) {
+				t.Fatalf("got dynamic tools %v, want %v", s.dynamicTools, tt.wantDynamic)
+			}
+			if fmt.Sprint(evicted) != fmt.Sprint(tt.wantEvicted) {
+				t.Fatalf("got evicted %v, want %v", evicted, tt.wantEvicted)
+			}
+			// 被淘汰或释放的 tool 可以再次获取
+			for _, name := range []string{"a", "b", "c"} {
+				_, alive := s.aliveToolsMap[name]
+				_, extra := s.extraToolsMap[name]
+				if alive == extra {
+					t.Fatalf("tool %s: alive=%v extra=%v", name, alive, extra)
+				}
+			}
+		})
+	}
+}
+
+func TestToolEvictionTokens(t *testing.T) {
+	ctx := context.Background()
+	config := &ToolListConfig{
+		Tools:      []tool.BaseTool{newTestTool("config", "d")},
+		ExtraTools: []tool.BaseTool{newTestTool("a", "d"), newTestTool("b", "d")},
+	}
+	tl, err := NewToolList(ctx, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 预算只够再放一个动态获取的 tool
+	budget := 0
+	for _, tokens := range tl.NewSession().toolTokens {
+		budget += tokens
+	}
+	cost, err := toolTokenCost(ctx, config.ExtraTools[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.MaxToolTokens = budget + cost
+	if tl, err = NewToolList(ctx, config); err != nil {
+		t.Fatal(err)
+	}
+	s := tl.NewSession()
+	mustLoadTool(t, s, "a")
+	_, evicted, _, err := s.loadTool(ctx, "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(evicted) != "[a]" || fmt.Sprint(s.dynamicTools) != "[b]" {
+		t.Fatalf("got evicted %v and dynamic tools %v, want [a] and [b]", evicted, s.dynamicTools)
+	}
+}
+
+func TestToolSessionIsolation(t *testing.T) {
+	ctx := context.Background()
+	extra := []string{"a", "b", "c", "d"}
+	var tools []tool.BaseTool
+	for _, name := range extra {
+		tools = append(tools, newTestTool(name, "d"))
+	}
+	// 获取 user message 指定的 tool 并调用，最后回答调用 ChatModel 时看到的非 special tool
+	llm := &scriptedModel{reply: func(in []*schema.Message, infos []*schema.ToolInfo) *schema.Message {
+		want := in[0].Content
+		switch len(in) {
+		case 1:
+			return toolCallMessage(newToolCall("g", SpecialGetToolToolName, fmt.Sprintf(`{"name":%q}`, want)))
+		case 3:
+			return toolCallMessage(newToolCall("c", want, `{"q":"x"}`))
+		}
+		var visible []string
+		for _, name := range toolNames(infos) {
+			if !strings.HasPrefix(name, "special_") {
+				visible = append(visible, name)
+			}
+		}
+		return schema.AssistantMessage(strings.Join(visible, ",")+": "+in[len(in)-1].Content, nil)
+	}}
+	a, err := NewAgent(ctx, &AgentConfig{ToolCallingModel: llm, ExtraTools: tools})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	results := make([]string, len(extra)*4)
+	errs := make([]error, len(results))
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			msg, err := a.Generate(ctx, []*schema.Message{schema.UserMessage(extra[i%len(extra)])})
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			results[i] = msg.Content
+		}(i)
+	}
+	wg.Wait()
+	for i, got := range results {
+		if errs[i] != nil {
+			t.Fatal(errs[i])
+		}
+		name := extra[i%len(extra)]
+		if want := name + ": " + name + " got x"; got != want {
+			t.Fatalf("run %d: got %q, want %q", i, got, want)
+		}
+	}
+}
+
+func TestDynamicToolMiddleware(t *testing.T) {
+	ctx := context.Background()
+	// 获取 extra 并调用，再调用没有获取的 hidden
+	llm := &scriptedModel{reply: func(in []*schema.Message, tools []*schema.ToolInfo) *schema.Message {
+		switch len(in) {
+		case 1:
+			return toolCallMessage(newToolCall("g", SpecialGetToolToolName, `{"name":"extra"}`))
+		case 3:
+			return toolCallMessage(newToolCall("e", "extra", `{"q":"a"}`))
+		case 5:
+			return toolCallMessage(newToolCall("h", "hidden", `{"q":"a"}`))
+		}
+		return schema.AssistantMessage(toolResults(in), nil)
+	}}
+	var seen []string
+	mw := compose.ToolMiddleware{Invokable: func(next compose.InvokableToolEndpoint) compose.InvokableToolEndpoint {
+		return func(ctx context.Context, in *compose.ToolInput) (*compose.ToolOutput, error) {
+			seen = append(seen, in.Name)
+			return next(ctx, in)
+		}
+	}}
+	a, err := NewAgent(ctx, &AgentConfig{
+		ToolCallingModel: llm,
+		ToolsConfig: compose.ToolsNodeConfig{
+			ToolCallMiddlewares: []compose.ToolMiddleware{mw},
+			ToolArgumentsHandler: func(ctx context.Context, name, arguments string) (string, error) {
+				return strings.ReplaceAll(arguments, `"a"`, `"b"`), nil
+			},
+			UnknownToolsHandler: func(ctx context.Context, name, input string) (string, error) {
+				return "unknown " + name, nil
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := WithTools(ctx, newTestTool("extra", "d"), newTestTool("hidden", "d"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := a.Generate(ctx, []*schema.Message{schema.UserMessage("hi")}, o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "g=get tool extra success; e=extra got b; h=unknown hidden"
+	if msg.Content != want {
+		t.Fatalf("got %q, want %q", msg.Content, want)
+	}
+	// 没有获取的 tool 与不存在的 tool 一样交给 UnknownToolsHandler
+	if got := fmt.Sprint(seen); got != "["+SpecialGetToolToolName+" extra]" {
+		t.Fatalf("got middleware calls %s", got)
+	}
+}